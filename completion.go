@@ -24,21 +24,94 @@ func listHasPrefix(pre string, ls []string) []string {
 	return list
 }
 
+// listGroupLevel lists the labels having the prefix pre one group at a time.
+// Labels in a deeper group than pre are folded into the group, such as "web/".
+func listGroupLevel(pre string, labels []string) []string {
+	list := make([]string, 0, len(labels))
+	seen := make(map[string]bool)
+
+	for _, l := range listHasPrefix(pre, labels) {
+		if n := strings.Index(l[len(pre):], "/"); n >= 0 {
+			l = l[:len(pre)+n+1]
+		}
+
+		if !seen[l] {
+			seen[l] = true
+			list = append(list, l)
+		}
+	}
+
+	return list
+}
+
 type completer struct {
 	cmds    []string
-	subCmds []string
+	subCmds map[string][]string
 	jobs    []string
+	jobDef  func(job string) (*rundeck.JobDef, error)
+	defs    map[string]*rundeck.JobDef
+}
+
+// definition returns the cached definition of the job, fetching it on first use.
+// A job whose definition cannot be fetched is cached as nil so that it is not refetched.
+func (c *completer) definition(job string) *rundeck.JobDef {
+	if c.defs == nil {
+		c.defs = make(map[string]*rundeck.JobDef)
+	}
+
+	if def, ok := c.defs[job]; ok {
+		return def
+	}
+
+	def, err := c.jobDef(job)
+	if err != nil {
+		def = nil
+	}
+	c.defs[job] = def
+
+	return def
+}
+
+// completeOption lists the option names not yet given in args,
+// or the allowed values when the previous arg is an option name.
+func (c *completer) completeOption(job string, args []string, target string) []string {
+	def := c.definition(job)
+	if def == nil {
+		return nil
+	}
+
+	if n := len(args); n > 0 && strings.HasPrefix(args[n-1], "-") && !strings.HasPrefix(target, "-") {
+		if opt := def.Option(strings.TrimPrefix(args[n-1], "-")); opt != nil {
+			return listHasPrefix(target, opt.Values)
+		}
+	}
+
+	given := make(map[string]bool, len(args))
+	for _, a := range args {
+		given[a] = true
+	}
+
+	names := make([]string, 0, len(def.Opts))
+	for _, opt := range def.Opts {
+		if name := "-" + opt.Name; !given[name] {
+			names = append(names, name)
+		}
+	}
+
+	return listHasPrefix(target, names)
 }
 
 func (c *completer) completeCmd(line string, pos int) (string, []string, string) {
-	pre, ls := line[:pos], line[pos:]
+	// pos counts runes, not bytes
+	rs := []rune(line)
+	pre, ls := string(rs[:pos]), string(rs[pos:])
 	pre = re2.ReplaceAllString(re.ReplaceAllString(pre, " "), "")
 
 	newPre := pre + " "
 	var list []string
 
-	switch ss := strings.Split(pre, " "); len(ss) {
-	case 1:
+	switch ss := strings.Split(pre, " "); {
+	case len(ss) == 1:
 		target := ss[0]
 		newPre = ""
 		if target == "" {
@@ -46,23 +119,28 @@ func (c *completer) completeCmd(line string, pos int) (string, []string, string)
 			break
 		}
 		list = listHasPrefix(target, c.cmds)
-	case 2:
+	case len(ss) == 2:
 		target := ss[1]
 		newPre = ss[0] + " "
 		if ss[0] == rundeck.CmdRun {
-			list = listHasPrefix(target, c.jobs)
+			list = listGroupLevel(target, c.jobs)
 			break
 		}
-		list = listHasPrefix(target, c.subCmds)
-	case 3:
+		list = listHasPrefix(target, c.subCmds[ss[0]])
+	case ss[0] == rundeck.CmdRun:
+		n := len(ss) - 1
+		newPre = strings.Join(ss[:n], " ") + " "
+		list = c.completeOption(ss[1], ss[2:n], ss[n])
+	case len(ss) == 3:
 		target := ss[2]
 		newPre = strings.Join(ss[:2], " ") + " "
 		if ss[1] == rundeck.SubCmdJob {
-			list = listHasPrefix(target, c.jobs)
+			list = listGroupLevel(target, c.jobs)
 		}
 	}
 
-	if len(list) == 1 {
+	// a group is completed up to "/" to go on with the jobs in it
+	if len(list) == 1 && !strings.HasSuffix(list[0], "/") {
 		list[0] += " "
 	}
 
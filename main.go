@@ -11,20 +11,58 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-func main() {
-	if !terminal.IsTerminal(0) {
-		fmt.Println("no support")
-		return
+const (
+	exitOK = iota
+	exitClientError
+	exitFailed
+	exitAborted
+	exitTimedOut
+	exitCancelled
+)
+
+// exitCode maps the error returned by a command to the process exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	if err == rundeck.ErrCancelled {
+		return exitCancelled
+	}
+
+	e, ok := err.(*rundeck.ExecutionError)
+	if !ok {
+		return exitClientError
+	}
+
+	switch e.Status {
+	case rundeck.StatusAborted:
+		return exitAborted
+	case rundeck.StatusTimedOut:
+		return exitTimedOut
+	default:
+		return exitFailed
 	}
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	// one-shot commands with token authentication work without a terminal, such as in CI
+	isTerminal := terminal.IsTerminal(0)
 
 	var confPath string
+	var dryRun bool
 	flag.StringVar(&confPath, "conf", "$HOME/.config/rundeck-cli/conf.json", "config path")
+	flag.BoolVar(&dryRun, "dry-run", false, "print POST requests instead of sending them")
 	flag.Parse()
 
 	conf, err := loadConf(os.ExpandEnv(confPath))
 	if err != nil {
 		fmt.Printf("failed to load config file. filepath:%s\n", confPath)
-		return
+		return exitClientError
 	}
 
 	line := liner.NewLiner()
@@ -34,49 +72,81 @@ func main() {
 
 	var rd *rundeck.Rundeck
 	if conf.Token == "" {
+		if !isTerminal {
+			fmt.Println("password authentication requires a terminal, set a token in the config file")
+			return exitClientError
+		}
+
 		username, err := line.Prompt("username: ")
 		if err != nil {
 			fmt.Println("failed to read 'username'")
-			return
+			return exitClientError
 		}
 		pass, err := line.PasswordPrompt("password: ")
 		if err != nil {
 			fmt.Println("failed to read 'password'")
-			return
+			return exitClientError
 		}
 
 		rd, err = rundeck.AuthWithPass(username, pass, conf.Schema, conf.Host, conf.Project, os.Stdout)
 		if err != nil {
 			fmt.Println("failed to password authentication")
-			return
+			return exitClientError
 		}
 	} else {
 		var err error
 		rd, err = rundeck.AuthWithToken(conf.Token, conf.Schema, conf.Host, conf.Project, os.Stdout)
 		if err != nil {
 			fmt.Println("failed to token authentication")
-			return
+			return exitClientError
 		}
 	}
 
+	rd.SetDryRun(dryRun)
+	confirm := func(msg string) bool {
+		answer, err := line.Prompt(msg + " [y/N] ")
+		if err != nil {
+			return false
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+	if isTerminal {
+		rd.SetConfirm(confirm)
+	}
+
 	if args := flag.Args(); len(args) > 0 {
-		if err := rd.Do(args[0], args[1:]); err != nil {
+		err := rd.Do(args[0], args[1:])
+		if err != nil {
 			fmt.Println(err)
 		}
 
-		return
+		return exitCode(err)
+	}
+
+	if !isTerminal {
+		fmt.Println("prompt mode requires a terminal")
+		return exitClientError
 	}
 
 	labels, err := rd.GetJobLabels()
 	if err != nil {
 		fmt.Println("failed to get jobs definition")
-		return
+		return exitClientError
+	}
+
+	subCmds := map[string][]string{
+		rundeck.CmdHelp:       rundeck.SubCmds(),
+		rundeck.CmdExecutions: rundeck.ExecutionsSubCmds(),
+		rundeck.CmdExport:     rundeck.SubCmds(),
 	}
 
 	cmpl := completer{
 		cmds:    rundeck.Cmds(),
-		subCmds: rundeck.SubCmds(),
+		subCmds: subCmds,
 		jobs:    labels,
+		jobDef:  rd.GetJobDefinition,
 	}
 	line.SetWordCompleter(cmpl.completeCmd)
 
@@ -84,7 +154,7 @@ func main() {
 		l, err := line.Prompt("rundeck> ")
 		if err != nil {
 			fmt.Println(err)
-			return
+			return exitOK
 		}
 
 		l = re.ReplaceAllString(strings.TrimSpace(l), " ")
@@ -99,10 +169,46 @@ func main() {
 			break
 		}
 
+		if cmd == rundeck.CmdRun {
+			// run flags may be given anywhere, so the job is looked up after parsing them
+			ra, err := rundeck.ParseRunArgs(args)
+			var jobDef *rundeck.JobDef
+			if err == nil {
+				jobDef, err = rd.GetJobDefinition(ra.Job)
+			}
+			if nf, ok := err.(*rundeck.JobNotFoundError); ok && len(nf.Suggestions) > 0 {
+				fmt.Println(err)
+				if !confirm(fmt.Sprintf("run job(%s) instead?", nf.Suggestions[0])) {
+					line.AppendHistory(l)
+					continue
+				}
+
+				ra.Job = nf.Suggestions[0]
+				jobDef, err = rd.GetJobDefinition(ra.Job)
+			}
+			if err == nil {
+				// keep the values of secure options out of the history
+				l = strings.Join(append([]string{cmd}, jobDef.Redact(args)...), " ")
+
+				// prompted values are passed apart from the args, so they are never parsed as args
+				if ra.Values, err = promptOptions(line, *jobDef, args); err == nil {
+					err = rd.Run(*ra)
+				}
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+
+			line.AppendHistory(l)
+			continue
+		}
+
 		if err := rd.Do(cmd, args); err != nil {
 			fmt.Println(err)
 		}
 
 		line.AppendHistory(l)
 	}
+
+	return exitOK
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mizkei/rundeck-cli/rundeck"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err    error
+		expect int
+	}{
+		{nil, exitOK},
+		{fmt.Errorf("job 'pppp' not found"), exitClientError},
+		{rundeck.ErrCancelled, exitCancelled},
+		{&rundeck.ExecutionError{ID: 1, Status: rundeck.StatusFailed}, exitFailed},
+		{&rundeck.ExecutionError{ID: 2, Status: rundeck.StatusAborted}, exitAborted},
+		{&rundeck.ExecutionError{ID: 3, Status: rundeck.StatusTimedOut}, exitTimedOut},
+	}
+
+	for _, tt := range tests {
+		if code := exitCode(tt.err); code != tt.expect {
+			t.Errorf("exit code not match. err:%v, got:%d, expect:%d", tt.err, code, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mizkei/rundeck-cli/rundeck"
+	"github.com/peterh/liner"
+)
+
+// promptOption asks for the value of opt until an acceptable one is entered.
+func promptOption(line *liner.State, opt rundeck.JobOption) (string, error) {
+	fmt.Printf("-%s: %s\n", opt.Name, opt.Desc)
+	if opt.Value != "" && !opt.Secret() {
+		fmt.Printf("\tdefault: %s\n", opt.Value)
+	}
+	if len(opt.Values) > 0 {
+		fmt.Printf("\tvalues: %s\n", strings.Join(opt.Values, ", "))
+	} else if opt.ValuesErr != nil {
+		fmt.Printf("\tvalues: unknown (%s)\n", opt.ValuesErr)
+	}
+
+	prompt := line.Prompt
+	if opt.Secret() {
+		prompt = line.PasswordPrompt
+	}
+
+	for {
+		v, err := prompt(fmt.Sprintf("-%s> ", opt.Name))
+		if err != nil {
+			return "", err
+		}
+
+		if !opt.Secret() {
+			v = strings.TrimSpace(v)
+		}
+		if v == "" {
+			v = opt.Value
+		}
+
+		switch {
+		case v == "":
+			fmt.Println("value required")
+		case !opt.Allows(v):
+			fmt.Println("value not allowed")
+		default:
+			return v, nil
+		}
+	}
+}
+
+// promptOptions asks for the required options of the job that are missing from the run args
+// and returns the values entered by option name.
+func promptOptions(line *liner.State, jobDef rundeck.JobDef, args []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, opt := range jobDef.MissingOptions(args) {
+		v, err := promptOption(line, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		values[opt.Name] = v
+	}
+
+	return values, nil
+}
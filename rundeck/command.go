@@ -1,8 +1,15 @@
 package rundeck
 
 const (
-	CmdRun  = "run"
-	CmdHelp = "help"
+	CmdRun        = "run"
+	CmdHelp       = "help"
+	CmdExecutions = "executions"
+	CmdAbort      = "abort"
+	CmdTail       = "tail"
+	CmdRetry      = "retry"
+	CmdState      = "state"
+	CmdOutput     = "output"
+	CmdExport     = "export"
 )
 
 const (
@@ -10,10 +17,20 @@ const (
 	SubCmdJobs = "jobs"
 )
 
+const (
+	SubCmdRunning   = "running"
+	SubCmdRecent    = "recent"
+	SubCmdScheduled = "scheduled"
+)
+
 func Cmds() []string {
-	return []string{CmdRun, CmdHelp}
+	return []string{CmdRun, CmdHelp, CmdExecutions, CmdAbort, CmdTail, CmdRetry, CmdState, CmdOutput, CmdExport}
 }
 
 func SubCmds() []string {
 	return []string{SubCmdJob, SubCmdJobs}
 }
+
+func ExecutionsSubCmds() []string {
+	return []string{SubCmdRunning, SubCmdRecent, SubCmdScheduled}
+}
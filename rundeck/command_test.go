@@ -5,7 +5,7 @@ import (
 )
 
 func TestCmds(t *testing.T) {
-	expectCmds := []string{"run", "help"}
+	expectCmds := []string{"run", "help", "executions", "abort", "tail", "retry", "state", "output", "export"}
 
 	cmds := Cmds()
 
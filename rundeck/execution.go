@@ -0,0 +1,713 @@
+package rundeck
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultRecentMax = 20
+)
+
+const (
+	StatusScheduled = "scheduled"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusAborted   = "aborted"
+	StatusTimedOut  = "timedout"
+)
+
+// ExecutionError is returned when an execution finished without succeeding.
+type ExecutionError struct {
+	ID     int
+	Status string
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("execution %d %s", e.ID, e.Status)
+}
+
+type DateTime struct {
+	Unixtime int64  `json:"unixtime"`
+	Date     string `json:"date"`
+}
+
+func (d DateTime) Time() time.Time {
+	return time.Unix(0, d.Unixtime*int64(time.Millisecond))
+}
+
+type Execution struct {
+	ID          int       `json:"id"`
+	Permalink   string    `json:"permalink"`
+	Status      string    `json:"status"`
+	User        string    `json:"user"`
+	DateStarted DateTime  `json:"date-started"`
+	DateEnded   *DateTime `json:"date-ended"`
+	Job         *Job      `json:"job"`
+	Desc        string    `json:"description"`
+	ArgString   string    `json:"argstring"`
+	FailedNodes []string  `json:"failedNodes"`
+}
+
+func (e Execution) label() string {
+	if e.Job == nil {
+		return "(adhoc)"
+	}
+	if e.Job.Label != "" {
+		return e.Job.Label
+	}
+	return label(e.Job.Group, e.Job.Name, e.Job.ID)
+}
+
+// labelJobs sets the labels of the jobs of execs to the labels of the loaded jobs,
+// so that colliding labels carry the same suffix as in getJobs.
+func (r *Rundeck) labelJobs(execs Executions) error {
+	ids := make(map[string]bool, len(execs))
+	for _, e := range execs {
+		if e.Job != nil {
+			ids[e.Job.ID] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	jobs, err := r.getJobs()
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[string]string, len(jobs))
+	for _, j := range jobs {
+		labels[j.ID] = j.Label
+	}
+
+	for _, e := range execs {
+		if e.Job != nil {
+			e.Job.Label = labels[e.Job.ID]
+		}
+	}
+
+	return nil
+}
+
+func (e Execution) duration() time.Duration {
+	end := time.Now()
+	if e.DateEnded != nil {
+		end = e.DateEnded.Time()
+	}
+	return end.Sub(e.DateStarted.Time()).Truncate(time.Second)
+}
+
+type Executions []Execution
+
+func (r *Rundeck) getExecution(id int) (*Execution, error) {
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/execution/%d", id), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("execution(%d) not found", id)
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var exec Execution
+	if err := json.NewDecoder(res.Body).Decode(&exec); err != nil {
+		return nil, err
+	}
+
+	return &exec, nil
+}
+
+type executionList struct {
+	Executions Executions `json:"executions"`
+}
+
+func (r *Rundeck) getExecutions(uri string, data url.Values) (Executions, error) {
+	res, err := r.request(http.MethodGet, uri, data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var list executionList
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	return list.Executions, nil
+}
+
+// reportStatus prints the final status of the execution.
+// It returns an *ExecutionError if the execution did not succeed.
+func (r *Rundeck) reportStatus(id int) error {
+	exec, err := r.getExecution(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.out, "done (%s)\n", exec.Status)
+	if exec.Status != StatusSucceeded {
+		return &ExecutionError{ID: exec.ID, Status: exec.Status}
+	}
+
+	return nil
+}
+
+func (r *Rundeck) getRunningExecutions() (Executions, error) {
+	return r.getExecutions(fmt.Sprintf("/project/%s/executions/running", r.project), url.Values{})
+}
+
+func (r *Rundeck) getScheduledExecutions() (Executions, error) {
+	data := url.Values{}
+	data.Set("statusFilter", StatusScheduled)
+
+	return r.getExecutions(fmt.Sprintf("/project/%s/executions", r.project), data)
+}
+
+func (r *Rundeck) getRecentExecutions(max int) (Executions, error) {
+	data := url.Values{}
+	data.Set("max", strconv.Itoa(max))
+
+	return r.getExecutions(fmt.Sprintf("/project/%s/executions", r.project), data)
+}
+
+func (r *Rundeck) displayExecutions(execs Executions) {
+	if len(execs) == 0 {
+		fmt.Fprintln(r.out, "no executions")
+		return
+	}
+
+	w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tJOB\tUSER\tSTARTED\tDURATION\tSTATUS")
+	for _, e := range execs {
+		duration := "-"
+		if e.Status != StatusScheduled {
+			duration = e.duration().String()
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.label(), e.User, e.DateStarted.Date, duration, e.Status)
+	}
+	w.Flush()
+}
+
+func (r *Rundeck) executions(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("sub command required")
+	}
+
+	subCmd, opts := args[0], args[1:]
+	flags, _, err := parseFlags(opts, map[string]bool{"max": true})
+	if err != nil {
+		return err
+	}
+
+	var execs Executions
+	switch subCmd {
+	case SubCmdRunning:
+		execs, err = r.getRunningExecutions()
+	case SubCmdScheduled:
+		execs, err = r.getScheduledExecutions()
+	case SubCmdRecent:
+		max := defaultRecentMax
+		if s, ok := flags["max"]; ok {
+			if max, err = strconv.Atoi(s); err != nil || max < 1 {
+				return fmt.Errorf("invalid max '%s'", s)
+			}
+		}
+		execs, err = r.getRecentExecutions(max)
+	default:
+		return fmt.Errorf("sub command '%s' not found", subCmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := r.labelJobs(execs); err != nil {
+		return err
+	}
+	r.displayExecutions(execs)
+
+	return nil
+}
+
+type Abort struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type AbortedExecution struct {
+	Status    string `json:"status"`
+	Permalink string `json:"permalink"`
+}
+
+type AbortResult struct {
+	Abort     Abort            `json:"abort"`
+	Execution AbortedExecution `json:"execution"`
+}
+
+// Abort requests to abort the execution and returns the abort status reported by the server.
+func (r *Rundeck) Abort(id int) (*AbortResult, error) {
+	res, err := r.request(http.MethodPost, fmt.Sprintf("/execution/%d/abort", id), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("execution(%d) not found", id)
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var result AbortResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *Rundeck) runningExecutionIDs(job string) ([]int, error) {
+	jb, err := r.findJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	execs, err := r.getRunningExecutions()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(execs))
+	for _, e := range execs {
+		if e.Job != nil && e.Job.ID == jb.ID {
+			ids = append(ids, e.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *Rundeck) displayAbort(id int, result AbortResult) {
+	fmt.Fprintf(r.out, "execution %d: abort %s\n", id, result.Abort.Status)
+	if result.Abort.Reason != "" {
+		fmt.Fprintln(r.out, "\t", result.Abort.Reason)
+	}
+}
+
+func (r *Rundeck) abort(args []string) error {
+	flags, rest, err := parseFlags(args, map[string]bool{"job": true})
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	if job, ok := flags["job"]; ok {
+		if ids, err = r.runningExecutionIDs(job); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no running executions of job(%s)", job)
+		}
+	} else {
+		if len(rest) < 1 {
+			return fmt.Errorf("execution id required")
+		}
+
+		id, err := parseExecutionID(rest[0])
+		if err != nil {
+			return err
+		}
+		ids = []int{id}
+	}
+
+	for _, id := range ids {
+		result, err := r.Abort(id)
+		if err == ErrDryRun {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		r.displayAbort(id, *result)
+	}
+
+	return nil
+}
+
+// prefixColors are the ANSI colors cycled through for the prefixes of multiplexed output.
+var prefixColors = []int{36, 32, 33, 35, 34, 31}
+
+// tailMulti follows several executions at once, prefixing each log line with "[label#id]",
+// and prints a summary of the final statuses.
+func (r *Rundeck) tailMulti(execs Executions, fromStart, color bool) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(execs))
+
+	for i, e := range execs {
+		prefix := fmt.Sprintf("[%s#%d]", e.label(), e.ID)
+		if color {
+			prefix = fmt.Sprintf("\x1b[%dm%s\x1b[0m", prefixColors[i%len(prefixColors)], prefix)
+		}
+
+		wg.Add(1)
+		go func(i, id int, prefix string) {
+			defer wg.Done()
+
+			errs[i] = r.pollOutput(id, fromStart, func(entries []Entry) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				for _, e := range entries {
+					fmt.Fprintln(r.out, prefix, e.Log)
+				}
+				return nil
+			})
+		}(i, e.ID, prefix)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed error
+	w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tJOB\tSTATUS")
+	for _, e := range execs {
+		exec, err := r.getExecution(e.ID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", exec.ID, e.label(), exec.Status)
+		if exec.Status != StatusSucceeded && failed == nil {
+			failed = &ExecutionError{ID: exec.ID, Status: exec.Status}
+		}
+	}
+	w.Flush()
+
+	return failed
+}
+
+func (r *Rundeck) tail(args []string) error {
+	flags, rest, err := parseFlags(args, map[string]bool{"all": false, "no-color": false})
+	if err != nil {
+		return err
+	}
+
+	if len(rest) < 1 {
+		return fmt.Errorf("execution id required")
+	}
+
+	execs := make(Executions, 0, len(rest))
+	for _, s := range rest {
+		id, err := parseExecutionID(s)
+		if err != nil {
+			return err
+		}
+
+		exec, err := r.getExecution(id)
+		if err != nil {
+			return err
+		}
+		execs = append(execs, *exec)
+	}
+
+	if len(execs) > 1 {
+		if err := r.labelJobs(execs); err != nil {
+			return err
+		}
+		return r.tailMulti(execs, flags["all"] != "", flags["no-color"] == "")
+	}
+
+	exec := execs[0]
+	fmt.Fprintf(r.out, "execution %d is %s (%s)\n", exec.ID, exec.Status, exec.Permalink)
+	if err := r.tailActivity(Act{ID: exec.ID, Permalink: exec.Permalink}, flags["all"] != ""); err != nil {
+		return err
+	}
+
+	return r.reportStatus(exec.ID)
+}
+
+func (r *Rundeck) retry(args []string) error {
+	flags, rest, err := parseFlags(args, map[string]bool{"failed-nodes": false, "filter": true, "as-user": true, "loglevel": true})
+	if err != nil {
+		return err
+	}
+
+	if len(rest) < 1 {
+		return fmt.Errorf("execution id required")
+	}
+
+	id, err := parseExecutionID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	exec, err := r.getExecution(id)
+	if err != nil {
+		return err
+	}
+
+	if exec.Job == nil {
+		return fmt.Errorf("execution(%d) is not a job execution", id)
+	}
+
+	// the execution does not tell the node filter, the user and the loglevel of the run,
+	// so they are the defaults of the job unless given again
+	data := url.Values{}
+	data.Set("argString", exec.ArgString)
+	if err := setRunFlags(data, flags); err != nil {
+		return err
+	}
+	if flags["failed-nodes"] != "" {
+		if _, ok := flags["filter"]; ok {
+			return fmt.Errorf("flag '--filter' cannot be used with '--failed-nodes'")
+		}
+		if len(exec.FailedNodes) == 0 {
+			return fmt.Errorf("execution(%d) has no failed nodes", id)
+		}
+		for _, node := range exec.FailedNodes {
+			if strings.ContainsAny(node, ", \t\"'") {
+				return fmt.Errorf("failed node '%s' cannot be used in a node filter, retry with '--filter'", node)
+			}
+		}
+		data.Set("filter", "name: "+strings.Join(exec.FailedNodes, ","))
+	}
+
+	act, err := r.runJob(*exec.Job, data)
+	if err == ErrDryRun {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.follow(*act)
+}
+
+type NodeState struct {
+	ExecutionState string `json:"executionState"`
+	StartTime      string `json:"startTime"`
+	EndTime        string `json:"endTime"`
+	Duration       int64  `json:"duration"`
+}
+
+type StepState struct {
+	ID             string               `json:"id"`
+	StepCtx        string               `json:"stepctx"`
+	NodeStep       bool                 `json:"nodeStep"`
+	ExecutionState string               `json:"executionState"`
+	Duration       int64                `json:"duration"`
+	NodeStates     map[string]NodeState `json:"nodeStates"`
+}
+
+type ExecutionState struct {
+	ExecutionID    int         `json:"executionId"`
+	ExecutionState string      `json:"executionState"`
+	Completed      bool        `json:"completed"`
+	TargetNodes    []string    `json:"targetNodes"`
+	Steps          []StepState `json:"steps"`
+}
+
+// nodes returns the names of the nodes the step ran on, in target order.
+func (es ExecutionState) nodes(step StepState) []string {
+	names := make([]string, 0, len(step.NodeStates))
+	for _, n := range es.TargetNodes {
+		if _, ok := step.NodeStates[n]; ok {
+			names = append(names, n)
+		}
+	}
+
+	if len(names) < len(step.NodeStates) {
+		names = names[:0]
+		for n := range step.NodeStates {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+	}
+
+	return names
+}
+
+func formatMillis(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+func (r *Rundeck) getExecutionState(id int) (*ExecutionState, error) {
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/execution/%d/state", id), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("execution(%d) not found", id)
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var state ExecutionState
+	if err := json.NewDecoder(res.Body).Decode(&state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (r *Rundeck) displayExecutionState(state ExecutionState) {
+	fmt.Fprintf(r.out, "execution %d: %s\n", state.ExecutionID, state.ExecutionState)
+
+	for _, step := range state.Steps {
+		fmt.Fprintf(r.out, "step %s %s %s\n", step.StepCtx, step.ExecutionState, formatMillis(step.Duration))
+
+		w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+		for _, n := range state.nodes(step) {
+			ns := step.NodeStates[n]
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", n, ns.ExecutionState, formatMillis(ns.Duration))
+		}
+		w.Flush()
+	}
+}
+
+func (r *Rundeck) state(args []string) error {
+	flags, rest, err := parseFlags(args, map[string]bool{"watch": false})
+	if err != nil {
+		return err
+	}
+
+	if len(rest) < 1 {
+		return fmt.Errorf("execution id required")
+	}
+
+	id, err := parseExecutionID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	watch := flags["watch"] != ""
+	for {
+		state, err := r.getExecutionState(id)
+		if err != nil {
+			return err
+		}
+
+		if watch {
+			// move the cursor home and clear the screen before redrawing
+			fmt.Fprint(r.out, "\x1b[H\x1b[2J")
+		}
+		r.displayExecutionState(*state)
+
+		if !watch || state.Completed {
+			break
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	return nil
+}
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// writeOutput writes the complete output of the execution to w and returns the number of entries.
+func (r *Rundeck) writeOutput(id int, w io.Writer, format string) (int, error) {
+	count := 0
+	enc := json.NewEncoder(w)
+
+	err := r.pollOutput(id, true, func(entries []Entry) error {
+		for _, e := range entries {
+			if format == FormatJSON {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			} else if _, err := fmt.Fprintln(w, e.Log); err != nil {
+				return err
+			}
+		}
+		count += len(entries)
+		return nil
+	})
+
+	return count, err
+}
+
+func (r *Rundeck) output(args []string) error {
+	flags, rest, err := parseFlags(args, map[string]bool{"save": true, "format": true})
+	if err != nil {
+		return err
+	}
+
+	if len(rest) < 1 {
+		return fmt.Errorf("execution id required")
+	}
+
+	id, err := parseExecutionID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	format := FormatText
+	if f, ok := flags["format"]; ok {
+		if f != FormatText && f != FormatJSON {
+			return fmt.Errorf("format '%s' not supported", f)
+		}
+		format = f
+	}
+
+	filename, ok := flags["save"]
+	if !ok {
+		_, err := r.writeOutput(id, r.out, format)
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	count, err := r.writeOutput(id, w, format)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.out, "saved %d entries to %s\n", count, filename)
+
+	return nil
+}
@@ -0,0 +1,911 @@
+package rundeck
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutions(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	testRes := `{
+  "paging": {
+    "count": 2,
+    "total": 2,
+    "offset": 0,
+    "max": 20
+  },
+  "executions": [
+    {
+      "id": 1,
+      "href": "",
+      "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/1",
+      "status": "succeeded",
+      "project": "test-rundeck",
+      "user": "admin",
+      "date-started": {
+        "unixtime": 1477980000000,
+        "date": "2016-11-01T06:00:00Z"
+      },
+      "date-ended": {
+        "unixtime": 1477980065000,
+        "date": "2016-11-01T06:01:05Z"
+      },
+      "job": {
+        "id": "1a2b3c4d-0000",
+        "averageDuration": 1000,
+        "name": "Deploy App",
+        "group": "",
+        "project": "test-rundeck",
+        "description": "deploy",
+        "href": "",
+        "permalink": "http://test.rundeck.in/project/test-rundeck/job/show/1a2b3c4d-0000"
+      },
+      "description": "deploy",
+      "argstring": null
+    },
+    {
+      "id": 2,
+      "href": "",
+      "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/2",
+      "status": "failed",
+      "project": "test-rundeck",
+      "user": "rundeck",
+      "date-started": {
+        "unixtime": 1477980000000,
+        "date": "2016-11-01T06:00:00Z"
+      },
+      "date-ended": {
+        "unixtime": 1477980003000,
+        "date": "2016-11-01T06:00:03Z"
+      },
+      "description": "uptime",
+      "argstring": null
+    }
+  ]
+}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[
+  {"id": "1a2b3c4d-0000", "name": "Deploy App", "group": null, "project": "test-rundeck"},
+  {"id": "5e6f7a8b-0000", "name": "deploy_app", "group": null, "project": "test-rundeck"}
+]`))
+		case fmt.Sprintf("/api/18/project/%s/executions/running", testProject):
+			w.Write([]byte(`{"paging":{"count":0,"total":0,"offset":0,"max":20},"executions":[]}`))
+		case fmt.Sprintf("/api/18/project/%s/executions", testProject):
+			if r.URL.Query().Get("statusFilter") == StatusScheduled {
+				w.Write([]byte(`{
+  "paging": {"count": 1, "total": 1, "offset": 0, "max": 20},
+  "executions": [
+    {
+      "id": 3,
+      "status": "scheduled",
+      "user": "admin",
+      "date-started": {"unixtime": 4070919845000, "date": "2099-01-01T03:04:05Z"},
+      "job": {"id": "1a2b3c4d-0000", "name": "Deploy App"}
+    }
+  ]
+}`))
+				break
+			}
+
+			if max := r.URL.Query().Get("max"); max != "5" {
+				t.Errorf("max not match. got:%s, expect:%s", max, "5")
+			}
+
+			w.Write([]byte(testRes))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("errors about sub command", func(t *testing.T) {
+		var err error
+		var w bytes.Buffer
+		rd.out = &w
+
+		err = rd.Do(CmdExecutions, []string{})
+		if err == nil || err.Error() != "sub command required" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "sub command required")
+		}
+
+		err = rd.Do(CmdExecutions, []string{"pppp"})
+		if err == nil || err.Error() != "sub command 'pppp' not found" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "sub command 'pppp' not found")
+		}
+
+		err = rd.Do(CmdExecutions, []string{SubCmdRecent, "--max", "x"})
+		if err == nil || err.Error() != "invalid max 'x'" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "invalid max 'x'")
+		}
+	})
+
+	t.Run("executions running", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdExecutions, []string{SubCmdRunning}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("no executions\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("executions scheduled", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdExecutions, []string{SubCmdScheduled}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(`ID  JOB                  USER   STARTED               DURATION  STATUS
+3   deploy-app@1a2b3c4d  admin  2099-01-01T03:04:05Z  -         scheduled
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("executions recent", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdExecutions, []string{SubCmdRecent, "--max=5"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(`ID  JOB                  USER     STARTED               DURATION  STATUS
+1   deploy-app@1a2b3c4d  admin    2016-11-01T06:00:00Z  1m5s      succeeded
+2   (adhoc)              rundeck  2016-11-01T06:00:00Z  3s        failed
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+}
+
+func TestAbort(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	aborted := make([]string, 0, 2)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[
+  {
+    "id": "test-id-0",
+    "name": "deploy",
+    "group": null,
+    "project": "test-rundeck",
+    "description": "deploy",
+    "href": "",
+    "permalink": "http://test.rundeck.in/project/test-rundeck/job/show/test-id-0"
+  }
+]`))
+		case fmt.Sprintf("/api/18/project/%s/executions/running", testProject):
+			w.Write([]byte(`{
+  "paging": {"count": 2, "total": 2, "offset": 0, "max": 20},
+  "executions": [
+    {"id": 3, "status": "running", "job": {"id": "test-id-0", "name": "deploy"}},
+    {"id": 4, "status": "running", "job": {"id": "test-id-1", "name": "done"}}
+  ]
+}`))
+		case "/api/18/execution/3/abort", "/api/18/execution/5/abort":
+			if r.Method != http.MethodPost {
+				t.Error("http method should be POST")
+			}
+
+			aborted = append(aborted, r.URL.Path)
+			w.Write([]byte(`{
+  "abort": {"status": "pending"},
+  "execution": {"id": "3", "status": "running", "href": "", "permalink": ""}
+}`))
+		case "/api/18/execution/999/abort":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": true, "errorCode": "api.error.item.doesnotexist", "message": "Execution ID does not exist: 999"}`))
+		case "/api/18/execution/7/abort":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": true, "errorCode": "api.error.item.unauthorized", "message": "Not authorized for action \"Kill\""}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("errors about arguments", func(t *testing.T) {
+		var err error
+		var w bytes.Buffer
+		rd.out = &w
+
+		err = rd.Do(CmdAbort, []string{})
+		if err == nil || err.Error() != "execution id required" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution id required")
+		}
+
+		err = rd.Do(CmdAbort, []string{"x"})
+		if err == nil || err.Error() != "invalid execution id 'x'" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "invalid execution id 'x'")
+		}
+	})
+
+	t.Run("abort id", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdAbort, []string{"5"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("execution 5: abort pending\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("abort errors", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+
+		err := rd.Do(CmdAbort, []string{"999"})
+		if err == nil || err.Error() != "execution(999) not found" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution(999) not found")
+		}
+
+		expectErr := `not authorized: Not authorized for action "Kill"`
+		err = rd.Do(CmdAbort, []string{"7"})
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+
+		if w.Len() != 0 {
+			t.Errorf("nothing should be printed. got:%s", w.String())
+		}
+	})
+
+	t.Run("abort job", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdAbort, []string{"--job", "deploy"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("execution 3: abort pending\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	expectAborted := []string{"/api/18/execution/5/abort", "/api/18/execution/3/abort"}
+	if !reflect.DeepEqual(aborted, expectAborted) {
+		t.Errorf("aborted executions not match. got:%v, expect:%v", aborted, expectAborted)
+	}
+}
+
+func TestTail(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	outputAPICount := 0
+	execStatus := StatusRunning
+	firstOffset := "0"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case "/api/18/execution/7":
+			w.Write([]byte(`{
+  "id": 7,
+  "href": "",
+  "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/7",
+  "status": "` + execStatus + `",
+  "project": "test-rundeck",
+  "user": "admin",
+  "date-started": {"unixtime": 1477980000000, "date": "2016-11-01T06:00:00Z"},
+  "job": {"id": "test-id-0", "name": "deploy"},
+  "description": "deploy",
+  "argstring": null
+}`))
+		case "/api/18/execution/8":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": true, "message": "Execution ID does not exist: 8"}`))
+		case "/api/18/execution/9":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": true, "message": "Not authorized for action \"Read\""}`))
+		case "/api/18/execution/7/output":
+			if r.URL.Query().Get("maxlines") == "1" {
+				// the log written before the first poll, of which only a page is returned
+				w.Write([]byte(`{"id": "7", "offset": "10", "completed": false, "totalSize": 100, "entries": [{"log": "test-log-0"}]}`))
+				break
+			}
+
+			if outputAPICount == 0 {
+				if offset := r.URL.Query().Get("offset"); offset != firstOffset {
+					t.Errorf("offset not match. got:%s, expect:%s", offset, firstOffset)
+				}
+
+				w.Write([]byte(`{
+  "id": "7",
+  "offset": "100",
+  "completed": false,
+  "lastModified": "1478336400000",
+  "entries": [{"log": "test-log-1"}]
+}`))
+			} else {
+				values := r.URL.Query()
+				if offset := values.Get("offset"); offset != "100" {
+					t.Errorf("offset not match. got:%s, expect:%s", offset, "100")
+				}
+
+				w.Write([]byte(`{
+  "id": "7",
+  "offset": "200",
+  "completed": true,
+  "lastModified": "1478336401000",
+  "entries": [{"log": "test-log-2"}]
+}`))
+				execStatus = StatusFailed
+			}
+			outputAPICount++
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("errors about arguments", func(t *testing.T) {
+		var err error
+		var w bytes.Buffer
+		rd.out = &w
+
+		err = rd.Do(CmdTail, []string{})
+		if err == nil || err.Error() != "execution id required" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution id required")
+		}
+
+		err = rd.Do(CmdTail, []string{"8"})
+		if err == nil || err.Error() != "execution(8) not found" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution(8) not found")
+		}
+
+		expectErr := `not authorized: Not authorized for action "Read"`
+		err = rd.Do(CmdTail, []string{"9"})
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("tail new lines", func(t *testing.T) {
+		outputAPICount, execStatus, firstOffset = 0, StatusRunning, "100"
+		var w bytes.Buffer
+		rd.out = &w
+		err := rd.Do(CmdTail, []string{"7"})
+		if e, ok := err.(*ExecutionError); !ok || e.ID != 7 || e.Status != StatusFailed {
+			t.Errorf("error not match. got:%v, expect:%s", err, "execution 7 failed")
+		}
+
+		expectOut := []byte(`execution 7 is running (http://test.rundeck.in/project/test-rundeck/execution/show/7)
+test-log-1
+test-log-2
+done (failed)
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("tail all", func(t *testing.T) {
+		outputAPICount, execStatus, firstOffset = 0, StatusRunning, "0"
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdTail, []string{"7", "--all"}); err == nil {
+			t.Error("should return error message")
+		}
+
+		expectOut := []byte(`execution 7 is running (http://test.rundeck.in/project/test-rundeck/execution/show/7)
+test-log-1
+test-log-2
+done (failed)
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	var posted url.Values
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/18/execution/9":
+			w.Write([]byte(`{
+  "id": 9,
+  "href": "",
+  "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/9",
+  "status": "failed",
+  "project": "test-rundeck",
+  "user": "admin",
+  "date-started": {"unixtime": 1477980000000, "date": "2016-11-01T06:00:00Z"},
+  "date-ended": {"unixtime": 1477980003000, "date": "2016-11-01T06:00:03Z"},
+  "job": {"id": "test-id-0", "name": "deploy"},
+  "description": "deploy",
+  "argstring": "-env prod",
+  "successfulNodes": ["web1"],
+  "failedNodes": ["web2", "web3"]
+}`))
+		case "/api/18/execution/11":
+			w.Write([]byte(`{"id": 11, "status": "succeeded", "description": "uptime", "argstring": null}`))
+		case "/api/18/execution/12":
+			w.Write([]byte(`{"id": 12, "status": "failed", "job": {"id": "test-id-0", "name": "deploy"}, "argstring": "-env prod", "failedNodes": ["web2", "db 1"]}`))
+		case "/api/18/job/test-id-0/executions":
+			if r.Method != http.MethodPost {
+				t.Error("http method should be POST")
+			}
+
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if posted, err = url.ParseQuery(string(b)); err != nil {
+				t.Error(err)
+			}
+
+			w.Write([]byte(`{"id": 10, "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/10"}`))
+		case "/api/18/execution/10/output":
+			w.Write([]byte(`{"id": "10", "offset": "100", "completed": true, "entries": [{"log": "test-log-1"}]}`))
+		case "/api/18/execution/10":
+			w.Write([]byte(`{"id": 10, "status": "succeeded"}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("errors about execution", func(t *testing.T) {
+		var err error
+		var w bytes.Buffer
+		rd.out = &w
+
+		err = rd.Do(CmdRetry, []string{})
+		if err == nil || err.Error() != "execution id required" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution id required")
+		}
+
+		err = rd.Do(CmdRetry, []string{"11"})
+		if err == nil || err.Error() != "execution(11) is not a job execution" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "execution(11) is not a job execution")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRetry, []string{"9"}); err != nil {
+			t.Error(err)
+		}
+
+		expectPosted := url.Values{"argString": {"-env prod"}}
+		if !reflect.DeepEqual(posted, expectPosted) {
+			t.Errorf("posted data not match. got:%v, expect:%v", posted, expectPosted)
+		}
+
+		expectOut := []byte(`job is running (http://test.rundeck.in/project/test-rundeck/execution/show/10)
+test-log-1
+done (succeeded)
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("retry failed nodes", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRetry, []string{"9", "--failed-nodes"}); err != nil {
+			t.Error(err)
+		}
+
+		expectPosted := url.Values{"argString": {"-env prod"}, "filter": {"name: web2,web3"}}
+		if !reflect.DeepEqual(posted, expectPosted) {
+			t.Errorf("posted data not match. got:%v, expect:%v", posted, expectPosted)
+		}
+	})
+
+	t.Run("retry with flags", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRetry, []string{"9", "--filter", "tags: web", "--as-user", "deployer", "--loglevel", "debug"}); err != nil {
+			t.Error(err)
+		}
+
+		expectPosted := url.Values{"argString": {"-env prod"}, "filter": {"tags: web"}, "asUser": {"deployer"}, "loglevel": {"DEBUG"}}
+		if !reflect.DeepEqual(posted, expectPosted) {
+			t.Errorf("posted data not match. got:%v, expect:%v", posted, expectPosted)
+		}
+	})
+
+	t.Run("errors about flags", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		tests := []struct {
+			args   []string
+			expect string
+		}{
+			{[]string{"9", "--failed-nodes", "--filter", "tags: web"}, "flag '--filter' cannot be used with '--failed-nodes'"},
+			{[]string{"9", "--loglevel", "loud"}, "invalid loglevel 'loud' (allowed: DEBUG, VERBOSE, INFO, WARN, ERROR)"},
+			{[]string{"12", "--failed-nodes"}, "failed node 'db 1' cannot be used in a node filter, retry with '--filter'"},
+		}
+
+		for _, tt := range tests {
+			err := rd.Do(CmdRetry, tt.args)
+			if err == nil || err.Error() != tt.expect {
+				t.Errorf("error message not match. got:%v, expect:%s", err, tt.expect)
+			}
+		}
+	})
+}
+
+func TestState(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	// refs: http://rundeck.org/2.6.4/api/index.html#execution-state
+	testRes := `{
+  "executionId": 12,
+  "serverNode": "test.rundeck.in",
+  "executionState": "FAILED",
+  "completed": true,
+  "targetNodes": ["web2", "web1"],
+  "allNodes": ["web2", "web1"],
+  "stepCount": 2,
+  "steps": [
+    {
+      "id": "1",
+      "stepctx": "1",
+      "nodeStep": true,
+      "executionState": "FAILED",
+      "duration": 3000,
+      "nodeStates": {
+        "web1": {"executionState": "SUCCEEDED", "duration": 1500},
+        "web2": {"executionState": "FAILED", "duration": 3000}
+      }
+    },
+    {
+      "id": "2",
+      "stepctx": "2",
+      "nodeStep": false,
+      "executionState": "NOT_STARTED"
+    }
+  ]
+}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case "/api/18/execution/12/state":
+			w.Write([]byte(testRes))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectState := `execution 12: FAILED
+step 1 FAILED 3s
+  web2  FAILED     3s
+  web1  SUCCEEDED  1.5s
+step 2 NOT_STARTED -
+`
+
+	t.Run("state", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdState, []string{"12"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(expectState)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("state watch", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdState, []string{"12", "--watch"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("\x1b[H\x1b[2J" + expectState)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+}
+
+func TestOutput(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	var polls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case "/api/18/execution/13/output":
+			switch offset := r.URL.Query().Get("offset"); offset {
+			case "0":
+				w.Write([]byte(`{
+  "id": "13",
+  "offset": "50",
+  "completed": false,
+  "lastModified": "1478336400000",
+  "entries": [
+    {"time": "15:00:00", "absolute_time": "2016-11-01T15:00:00Z", "log": "test-log-1", "level": "NORMAL", "user": "rundeck", "stepctx": "1", "node": "web1"}
+  ]
+}`))
+			case "50":
+				w.Write([]byte(`{
+  "id": "13",
+  "offset": "100",
+  "completed": true,
+  "lastModified": "1478336401000",
+  "entries": [
+    {"time": "15:00:01", "absolute_time": "2016-11-01T15:00:01Z", "log": "test-log-2", "level": "ERROR", "user": "rundeck", "stepctx": "2", "node": "web1"}
+  ]
+}`))
+			default:
+				t.Errorf("offset is wrong. offset:%s", offset)
+			}
+		case "/api/18/execution/14/output":
+			// the execution has completed but its log is still being written
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"id": "14", "offset": "0", "completed": false, "execCompleted": true, "entries": []}`))
+				break
+			}
+			w.Write([]byte(`{"id": "14", "offset": "50", "completed": true, "execCompleted": true, "entries": [{"log": "test-log-1"}]}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	dir, err := ioutil.TempDir("", "rundeck-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("errors about format", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+
+		err := rd.Do(CmdOutput, []string{"13", "--format", "xml"})
+		if err == nil || err.Error() != "format 'xml' not supported" {
+			t.Errorf("error message not match. got:%v, expect:%s", err, "format 'xml' not supported")
+		}
+	})
+
+	t.Run("output text", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		filename := filepath.Join(dir, "13.log")
+		start := time.Now()
+		if err := rd.Do(CmdOutput, []string{"13", "--save", filename}); err != nil {
+			t.Error(err)
+		}
+		if time.Since(start) >= time.Second {
+			t.Error("pages with entries should be fetched without waiting")
+		}
+
+		expectOut := []byte(fmt.Sprintf("saved 2 entries to %s\n", filename))
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expectFile := []byte("test-log-1\ntest-log-2\n")
+		if !bytes.Equal(b, expectFile) {
+			t.Errorf("file not match.\ngot:\n%s\nexpect:\n%s", string(b), string(expectFile))
+		}
+	})
+
+	t.Run("output of completed execution", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		start := time.Now()
+		if err := rd.Do(CmdOutput, []string{"14"}); err != nil {
+			t.Error(err)
+		}
+		if time.Since(start) < time.Second {
+			t.Error("empty pages should be fetched after waiting")
+		}
+		if polls != 2 {
+			t.Errorf("polls not match. got:%d, expect:%d", polls, 2)
+		}
+
+		expectOut := []byte("test-log-1\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("output json", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdOutput, []string{"13", "--format=json"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(`{"time":"15:00:00","absolute_time":"2016-11-01T15:00:00Z","level":"NORMAL","user":"rundeck","node":"web1","stepctx":"1","log":"test-log-1"}
+{"time":"15:00:01","absolute_time":"2016-11-01T15:00:01Z","level":"ERROR","user":"rundeck","node":"web1","stepctx":"2","log":"test-log-2"}
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+}
+
+func TestTailMulti(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[{"id": "test-id-0", "name": "deploy", "group": null, "project": "test-rundeck"}]`))
+		case "/api/18/execution/20":
+			w.Write([]byte(`{"id": 20, "status": "succeeded", "job": {"id": "test-id-0", "name": "deploy"}}`))
+		case "/api/18/execution/21":
+			w.Write([]byte(`{"id": 21, "status": "failed", "job": {"id": "test-id-0", "name": "deploy"}}`))
+		case "/api/18/execution/20/output":
+			w.Write([]byte(`{"id": "20", "offset": "10", "completed": true, "entries": [{"log": "test-log-20"}]}`))
+		case "/api/18/execution/21/output":
+			w.Write([]byte(`{"id": "21", "offset": "10", "completed": true, "entries": [{"log": "test-log-21"}]}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("tail multiple executions", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		err := rd.Do(CmdTail, []string{"20", "21", "--all", "--no-color"})
+		if e, ok := err.(*ExecutionError); !ok || e.ID != 21 || e.Status != StatusFailed {
+			t.Errorf("error not match. got:%v, expect:%s", err, "execution 21 failed")
+		}
+
+		lines := strings.SplitAfter(w.String(), "\n")
+		sort.Strings(lines[:2])
+
+		expectOut := `[deploy#20] test-log-20
+[deploy#21] test-log-21
+ID  JOB     STATUS
+20  deploy  succeeded
+21  deploy  failed
+`
+		if out := strings.Join(lines, ""); out != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", out, expectOut)
+		}
+	})
+
+	t.Run("tail multiple executions with color", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		rd.Do(CmdTail, []string{"20", "21", "--all"})
+
+		if !strings.Contains(w.String(), "\x1b[36m[deploy#20]\x1b[0m test-log-20\n") {
+			t.Errorf("colored prefix not found.\ngot:\n%s", w.String())
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package rundeck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ExportYAML = "yaml"
+	ExportXML  = "xml"
+)
+
+// exportJob writes the definition of the job as the server returns it.
+func (r *Rundeck) exportJob(jb Job, w io.Writer, format string) error {
+	data := url.Values{}
+	data.Set("format", format)
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/job/%s", jb.ID), data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// exportJobs writes the definitions of the jobs in the group, or of all jobs, in one bundle.
+func (r *Rundeck) exportJobs(group string, w io.Writer, format string) error {
+	data := url.Values{}
+	data.Set("format", format)
+	if group != "" {
+		data.Set("groupPath", group)
+	}
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/project/%s/jobs/export", r.project), data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// inGroup reports whether the job is in the group or in one of its subgroups.
+func (jb Job) inGroup(group string) bool {
+	group = strings.Trim(group, "/")
+	return group == "" || jb.Group == group || strings.HasPrefix(jb.Group, group+"/")
+}
+
+// exportJobFile writes the definition of the job to a file named after its label in dir.
+func (r *Rundeck) exportJobFile(jb Job, dir, format string) (string, error) {
+	filename := filepath.Join(dir, filepath.FromSlash(jb.Label)+"."+format)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := r.exportJob(jb, f, format); err != nil {
+		return "", err
+	}
+
+	return filename, f.Close()
+}
+
+// saveExport writes the output of export to the file, or to r.out without a file.
+func (r *Rundeck) saveExport(filename string, export func(w io.Writer) error) error {
+	if filename == "" {
+		return export(r.out)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := export(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.out, "exported to %s\n", filename)
+
+	return nil
+}
+
+func (r *Rundeck) export(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("sub command required")
+	}
+
+	subCmd := args[0]
+	flags, rest, err := parseFlags(args[1:], map[string]bool{"format": true, "out": true, "dir": true, "group": true})
+	if err != nil {
+		return err
+	}
+
+	format := ExportYAML
+	if f, ok := flags["format"]; ok {
+		if f != ExportYAML && f != ExportXML {
+			return fmt.Errorf("format '%s' not supported", f)
+		}
+		format = f
+	}
+
+	if flags["out"] != "" && flags["dir"] != "" {
+		return fmt.Errorf("flag '--out' cannot be used with '--dir'")
+	}
+
+	switch subCmd {
+	case SubCmdJob:
+		if len(rest) < 1 {
+			return fmt.Errorf("job name required")
+		}
+		if _, ok := flags["group"]; ok {
+			return fmt.Errorf("flag '--group' requires 'export jobs'")
+		}
+
+		jb, err := r.findJob(rest[0])
+		if err != nil {
+			return err
+		}
+
+		if dir := flags["dir"]; dir != "" {
+			filename, err := r.exportJobFile(*jb, dir, format)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(r.out, "exported job(%s) to %s\n", jb.Label, filename)
+			return nil
+		}
+
+		return r.saveExport(flags["out"], func(w io.Writer) error {
+			return r.exportJob(*jb, w, format)
+		})
+	case SubCmdJobs:
+		group := flags["group"]
+
+		dir := flags["dir"]
+		if dir == "" {
+			return r.saveExport(flags["out"], func(w io.Writer) error {
+				return r.exportJobs(group, w, format)
+			})
+		}
+
+		jobs, err := r.getJobs()
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, jb := range jobs {
+			if !jb.inGroup(group) {
+				continue
+			}
+
+			if _, err := r.exportJobFile(jb, dir, format); err != nil {
+				return fmt.Errorf("failed to export job(%s): %s", jb.Label, err)
+			}
+			count++
+		}
+
+		fmt.Fprintf(r.out, "exported %d jobs to %s\n", count, dir)
+	default:
+		return fmt.Errorf("sub command '%s' not found", subCmd)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package rundeck
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	testJobs := `[
+  {"id": "test-id-0", "name": "deploy", "group": "web", "project": "test-rundeck", "description": ""},
+  {"id": "test-id-1", "name": "backup", "group": "db", "project": "test-rundeck", "description": ""},
+  {"id": "test-id-2", "name": "restart", "group": "web/app", "project": "test-rundeck", "description": ""}
+]`
+	testDefs := map[string]string{
+		"test-id-0": "- name: deploy\n  group: web\n  id: test-id-0\n",
+		"test-id-1": "- name: backup\n  group: db\n  id: test-id-1\n",
+		"test-id-2": "- name: restart\n  group: web/app\n  id: test-id-2\n",
+	}
+	testXML := "<joblist>\n  <job>\n    <name>deploy</name>\n  </job>\n</joblist>\n"
+	testBundle := testDefs["test-id-0"] + testDefs["test-id-2"]
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		format := r.URL.Query().Get("format")
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(testJobs))
+		case fmt.Sprintf("/api/18/project/%s/jobs/export", testProject):
+			if g := r.URL.Query().Get("groupPath"); g != "web" {
+				t.Errorf("groupPath not match. got:%s, expect:%s", g, "web")
+			}
+			w.Write([]byte(testBundle))
+		case "/api/18/job/test-id-0", "/api/18/job/test-id-1", "/api/18/job/test-id-2":
+			id := filepath.Base(r.URL.Path)
+			if format == ExportXML {
+				w.Write([]byte(testXML))
+				break
+			}
+			w.Write([]byte(testDefs[id]))
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "rundeck-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("job to stdout", func(t *testing.T) {
+		buf.Reset()
+		if err := rd.Do(CmdExport, []string{SubCmdJob, "web/deploy"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != testDefs["test-id-0"] {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", buf.String(), testDefs["test-id-0"])
+		}
+	})
+
+	t.Run("job to file in xml", func(t *testing.T) {
+		buf.Reset()
+		filename := filepath.Join(dir, "deploy.xml")
+		if err := rd.Do(CmdExport, []string{SubCmdJob, "web/deploy", "--format", "xml", "--out", filename}); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != testXML {
+			t.Errorf("file not match.\ngot:\n%s\nexpect:\n%s", b, testXML)
+		}
+
+		if expect := fmt.Sprintf("exported to %s\n", filename); buf.String() != expect {
+			t.Errorf("output not match. got:%s, expect:%s", buf.String(), expect)
+		}
+	})
+
+	t.Run("jobs bundle", func(t *testing.T) {
+		buf.Reset()
+		if err := rd.Do(CmdExport, []string{SubCmdJobs, "--group", "web"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != testBundle {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", buf.String(), testBundle)
+		}
+	})
+
+	t.Run("jobs to dir", func(t *testing.T) {
+		buf.Reset()
+		out := filepath.Join(dir, "jobs")
+		if err := rd.Do(CmdExport, []string{SubCmdJobs, "--group", "web", "--dir", out}); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, id := range map[string]string{"web/deploy.yaml": "test-id-0", "web/app/restart.yaml": "test-id-2"} {
+			b, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != testDefs[id] {
+				t.Errorf("file %s not match.\ngot:\n%s\nexpect:\n%s", name, b, testDefs[id])
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(out, "db")); !os.IsNotExist(err) {
+			t.Error("job out of the group should not be exported")
+		}
+
+		if expect := fmt.Sprintf("exported 2 jobs to %s\n", out); buf.String() != expect {
+			t.Errorf("output not match. got:%s, expect:%s", buf.String(), expect)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			args   []string
+			expect string
+		}{
+			{[]string{}, "sub command required"},
+			{[]string{"pppp"}, "sub command 'pppp' not found"},
+			{[]string{SubCmdJob}, "job name required"},
+			{[]string{SubCmdJob, "web/deploy", "--format", "json"}, "format 'json' not supported"},
+			{[]string{SubCmdJobs, "--out", "a", "--dir", "b"}, "flag '--out' cannot be used with '--dir'"},
+			{[]string{SubCmdJob, "web/deploy", "--group", "web"}, "flag '--group' requires 'export jobs'"},
+		}
+
+		for _, tt := range tests {
+			err := rd.Do(CmdExport, tt.args)
+			if err == nil || err.Error() != tt.expect {
+				t.Errorf("error message not match. got:%v, expect:%s", err, tt.expect)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package rundeck
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,28 +11,102 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 const (
-	baseURLFmt = "%s://%s/api/16"
+	baseURLFmt = "%s://%s/api/18"
+
+	// runAtTimeFmt is the ISO-8601 format the API expects for runAtTime.
+	runAtTimeFmt = "2006-01-02T15:04:05-0700"
 )
 
+// ErrDryRun is returned for a POST request that is not sent in dry-run mode.
+var ErrDryRun = errors.New("request not sent in dry-run mode")
+
+// ErrCancelled is returned when a run is declined at the node preview.
+var ErrCancelled = errors.New("run cancelled")
+
+// runFlags are the "--name" flags accepted by the run command.
+// Flags mapped to true take a value.
+var runFlags = map[string]bool{
+	"detach":       false,
+	"json":         false,
+	"options-file": true,
+	"filter":       true,
+	"preview":      false,
+	"at":           true,
+	"as-user":      true,
+	"loglevel":     true,
+}
+
+// RunArgs are the args of the run command.
+type RunArgs struct {
+	Job   string
+	Opts  []string
+	Flags map[string]string
+
+	// Values are option values given apart from Opts, such as prompted ones.
+	// They are used as they are, without being parsed as args.
+	Values map[string]string
+}
+
+// ParseRunArgs separates the args of the run command into the job, its options and the flags,
+// which may be given anywhere in args.
+func ParseRunArgs(args []string) (*RunArgs, error) {
+	flags, rest, err := parseFlags(args, runFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < 1 {
+		return nil, fmt.Errorf("job name required")
+	}
+
+	if flags["json"] != "" && flags["detach"] == "" {
+		return nil, fmt.Errorf("flag '--json' requires '--detach'")
+	}
+
+	return &RunArgs{Job: rest[0], Opts: rest[1:], Flags: flags}, nil
+}
+
+// logLevels are the log levels accepted by the run job API.
+var logLevels = []string{"DEBUG", "VERBOSE", "INFO", "WARN", "ERROR"}
+
 type JobOption struct {
-	Name       string `yaml:"name"`
-	IsRequired bool   `yaml:"required"`
-	Desc       string `yaml:"description"`
+	Name          string   `yaml:"name"`
+	IsRequired    bool     `yaml:"required"`
+	Desc          string   `yaml:"description"`
+	Value         string   `yaml:"value"`
+	Values        []string `yaml:"values"`
+	IsEnforced    bool     `yaml:"enforced"`
+	IsMultiValued bool     `yaml:"multivalued"`
+	Delimiter     string   `yaml:"delimiter"`
+	IsSecure      bool     `yaml:"secure"`
+	IsExposed     bool     `yaml:"valueExposed"`
+	ValuesURL     string   `yaml:"valuesUrl"`
+
+	// ValuesErr is why the values of ValuesURL are unknown.
+	ValuesErr error `yaml:"-"`
+}
+
+type NodeFilters struct {
+	Filter string `yaml:"filter"`
 }
 
 type JobDef struct {
-	Name  string      `yaml:"name"`
-	Desc  string      `yaml:"description"`
-	Opts  []JobOption `yaml:"options"`
-	Label string      `yaml:"-"`
+	Name        string      `yaml:"name"`
+	Group       string      `yaml:"group"`
+	Desc        string      `yaml:"description"`
+	Opts        []JobOption `yaml:"options"`
+	NodeFilters NodeFilters `yaml:"nodefilters"`
+	Label       string      `yaml:"-"`
 }
 
 type JobDefList []JobDef
@@ -39,9 +114,13 @@ type JobDefList []JobDef
 type Job struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
+	Group     string `json:"group"`
 	Desc      string `json:"description"`
 	Permalink string `json:"permalink"`
 	Label     string `json:"-"`
+
+	// baseLabel is the label before it was made unique, empty unless it collides.
+	baseLabel string
 }
 
 type Jobs []Job
@@ -55,20 +134,120 @@ func (js Jobs) pick(job string) *Job {
 	return nil
 }
 
+// resolveCollisions gives the jobs sharing a label a stable suffix of their ID,
+// such as "deploy-app@1a2b3c4d".
+func (js Jobs) resolveCollisions() {
+	count := make(map[string]int, len(js))
+	for _, j := range js {
+		count[j.Label]++
+	}
+
+	for i, j := range js {
+		if count[j.Label] < 2 {
+			continue
+		}
+		js[i].baseLabel = j.Label
+		js[i].Label = j.Label + "@" + shortID(j.ID)
+	}
+}
+
+// candidates returns the labels of the jobs whose label collided on job.
+func (js Jobs) candidates(job string) []string {
+	var labels []string
+	for _, j := range js {
+		if j.baseLabel != "" && j.baseLabel == job {
+			labels = append(labels, j.Label)
+		}
+	}
+	return labels
+}
+
+// filter returns the jobs whose label contains the runes of pattern in order.
+func (js Jobs) filter(pattern string) Jobs {
+	pattern = strings.ToLower(pattern)
+
+	var jobs Jobs
+	for _, j := range js {
+		if isSubsequence(pattern, j.Label) {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// labels returns the labels of the jobs.
+func (js Jobs) labels() []string {
+	labels := make([]string, 0, len(js))
+	for _, j := range js {
+		labels = append(labels, j.Label)
+	}
+	return labels
+}
+
+// JobNotFoundError is returned when no job has the label, with the closest labels as suggestions.
+type JobNotFoundError struct {
+	Label       string
+	Suggestions []string
+}
+
+func (e *JobNotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("job(%s) not found", e.Label)
+	}
+
+	return fmt.Sprintf("job(%s) not found, did you mean:\n\t%s", e.Label, strings.Join(e.Suggestions, "\n\t"))
+}
+
+// APIError is the error reported by the API.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  string `json:"errorCode"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return fmt.Sprintf("not authorized: %s", e.Message)
+	}
+	return fmt.Sprintf("api error (%d): %s", e.StatusCode, e.Message)
+}
+
+// checkResponse returns an *APIError if the response is an error.
+func checkResponse(res *http.Response) error {
+	if res.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: res.StatusCode}
+	if err := json.NewDecoder(res.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = res.Status
+	}
+
+	return apiErr
+}
+
 type Act struct {
 	ID        int    `json:"id"`
 	Permalink string `json:"permalink"`
 }
 
 type Entry struct {
-	Log string `json:"log"`
+	Time         string `json:"time,omitempty"`
+	AbsoluteTime string `json:"absolute_time,omitempty"`
+	Level        string `json:"level,omitempty"`
+	User         string `json:"user,omitempty"`
+	Node         string `json:"node,omitempty"`
+	StepCtx      string `json:"stepctx,omitempty"`
+	Log          string `json:"log"`
 }
 
 type Output struct {
-	Entries      []Entry `json:"entries"`
-	Offset       int     `json:"offset,string"`
-	LastModified int     `json:"lastModified,string"`
-	Completed    bool    `json:"completed"`
+	Entries       []Entry `json:"entries"`
+	Offset        int     `json:"offset,string"`
+	LastModified  int     `json:"lastModified,string"`
+	Completed     bool    `json:"completed"`
+	ExecCompleted bool    `json:"execCompleted"`
+	TotalSize     int     `json:"totalSize"`
 }
 
 type Rundeck struct {
@@ -78,6 +257,52 @@ type Rundeck struct {
 	baseURL      string
 	project      string
 	out          io.Writer
+	confirm      func(msg string) bool
+	dryRun       bool
+
+	valuesMu    sync.Mutex
+	valuesCache map[string]optionValues
+}
+
+// SetDryRun sets whether POST requests are printed instead of sent.
+// GET requests are still sent, so that jobs and options are resolved and validated for real.
+func (r *Rundeck) SetDryRun(dryRun bool) {
+	r.dryRun = dryRun
+}
+
+// SetConfirm sets the function used to ask the user a yes/no question before acting,
+// such as running a job on the nodes listed by "run --preview".
+func (r *Rundeck) SetConfirm(confirm func(msg string) bool) {
+	r.confirm = confirm
+}
+
+// cloneHeader copies r.header for a single request.
+// The client's cookie jar adds cookies to the request header, so sharing r.header
+// would pile them up across requests and race when executions are tailed concurrently.
+func (r *Rundeck) cloneHeader() http.Header {
+	header := make(http.Header, len(r.header))
+	for k, v := range r.header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	return header
+}
+
+// displayRequest prints a request that is not sent in dry-run mode.
+func (r *Rundeck) displayRequest(method, u string, data url.Values) {
+	fmt.Fprintf(r.out, "dry-run: %s %s\n", method, u)
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range data[k] {
+			fmt.Fprintf(r.out, "\t%s=%s\n", k, v)
+		}
+	}
 }
 
 func (r *Rundeck) request(method, uri string, data url.Values) (*http.Response, error) {
@@ -87,35 +312,17 @@ func (r *Rundeck) request(method, uri string, data url.Values) (*http.Response,
 	}
 	u.Path = path.Join(u.Path, uri)
 
-	// why this process is necessary {{
-	ucs := make([]string, 0, 4)
-	for _, s := range r.header["Cookie"] {
-		ls := strings.Split(s, "; ")
-
-		cs := make(map[string]struct{})
-		for _, s := range ls {
-			cs[s] = struct{}{}
+	if method == http.MethodPost {
+		if r.dryRun {
+			r.displayRequest(method, u.String(), data)
+			return nil, ErrDryRun
 		}
 
-		us := ""
-		if len(cs) > 0 {
-			list := make([]string, 0, 4)
-			for k := range cs {
-				list = append(list, k)
-			}
-			us = strings.Join(list, "; ")
-		}
-		ucs = append(ucs, us)
-	}
-	r.header["Cookie"] = ucs
-	// }}
-
-	if method == http.MethodPost {
 		req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(data.Encode()))
 		if err != nil {
 			return nil, err
 		}
-		req.Header = r.header
+		req.Header = r.cloneHeader()
 
 		return r.client.Do(req)
 	}
@@ -124,7 +331,7 @@ func (r *Rundeck) request(method, uri string, data url.Values) (*http.Response,
 	if err != nil {
 		return nil, err
 	}
-	req.Header = r.header
+	req.Header = r.cloneHeader()
 	req.URL.RawQuery = data.Encode()
 
 	return r.client.Do(req)
@@ -136,12 +343,7 @@ func (r *Rundeck) GetJobLabels() ([]string, error) {
 		return nil, err
 	}
 
-	labels := make([]string, 0, len(jobs))
-	for _, j := range jobs {
-		labels = append(labels, j.Label)
-	}
-
-	return labels, nil
+	return jobs.labels(), nil
 }
 
 func (r *Rundeck) getJobs() (Jobs, error) {
@@ -157,17 +359,46 @@ func (r *Rundeck) getJobs() (Jobs, error) {
 	}
 
 	for i := range jobs {
-		jobs[i].Label = normalize(jobs[i].Name)
+		jobs[i].Label = label(jobs[i].Group, jobs[i].Name, jobs[i].ID)
 	}
+	jobs.resolveCollisions()
 
 	return jobs, nil
 }
 
-func (r *Rundeck) getJobDefinition(job string) (*JobDef, error) {
+// getJob fetches the job by its ID without looking up the labels.
+func (r *Rundeck) getJob(id string) (*Job, error) {
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/job/%s/info", id), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("job(%s) not found", id)
+	}
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var jb Job
+	if err := json.NewDecoder(res.Body).Decode(&jb); err != nil {
+		return nil, err
+	}
+	jb.Label = label(jb.Group, jb.Name, jb.ID)
+
+	return &jb, nil
+}
+
+func (r *Rundeck) findJob(job string) (*Job, error) {
 	if job == "" {
 		return nil, fmt.Errorf("job required")
 	}
 
+	if id, ok := parseJobID(job); ok {
+		return r.getJob(id)
+	}
+
 	jobs, err := r.getJobs()
 	if err != nil {
 		return nil, err
@@ -175,9 +406,30 @@ func (r *Rundeck) getJobDefinition(job string) (*JobDef, error) {
 
 	jb := jobs.pick(job)
 	if jb == nil {
-		return nil, fmt.Errorf("job(%s) not found", job)
+		if c := jobs.candidates(job); len(c) > 0 {
+			return nil, fmt.Errorf("job(%s) is ambiguous, candidates:\n\t%s", job, strings.Join(c, "\n\t"))
+		}
+		return nil, &JobNotFoundError{Label: job, Suggestions: suggest(job, jobs.labels())}
+	}
+
+	return jb, nil
+}
+
+// GetJobDefinition returns the definition of the job labeled job.
+func (r *Rundeck) GetJobDefinition(job string) (*JobDef, error) {
+	return r.getJobDefinition(job)
+}
+
+func (r *Rundeck) getJobDefinition(job string) (*JobDef, error) {
+	jb, err := r.findJob(job)
+	if err != nil {
+		return nil, err
 	}
 
+	return r.fetchJobDefinition(*jb)
+}
+
+func (r *Rundeck) fetchJobDefinition(jb Job) (*JobDef, error) {
 	data := url.Values{}
 	data.Set("format", "yaml")
 	res, err := r.request(http.MethodGet, fmt.Sprintf("/job/%s", jb.ID), data)
@@ -201,20 +453,27 @@ func (r *Rundeck) getJobDefinition(job string) (*JobDef, error) {
 	}
 
 	jobDef := jdl[0]
-	jobDef.Label = normalize(jobDef.Name)
+	jobDef.Label = jb.Label
+	if jobDef.Label == "" {
+		jobDef.Label = label(jobDef.Group, jobDef.Name, jb.ID)
+	}
+
+	jobDef.fetchValues(r)
 
 	return &jobDef, nil
 }
 
-func (r *Rundeck) runJob(job Job, opts []string) (*Act, error) {
-	data := url.Values{}
-	data.Set("argString", strings.Join(opts, " "))
+func (r *Rundeck) runJob(job Job, data url.Values) (*Act, error) {
 	res, err := r.request(http.MethodPost, fmt.Sprintf("/job/%s/executions", job.ID), data)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
 	var act Act
 	if err := json.NewDecoder(res.Body).Decode(&act); err != nil {
 		return nil, err
@@ -223,36 +482,64 @@ func (r *Rundeck) runJob(job Job, opts []string) (*Act, error) {
 	return &act, nil
 }
 
-func (r *Rundeck) tailActivity(act Act) error {
+// fetchOutput fetches a page of the execution output.
+func (r *Rundeck) fetchOutput(id int, data url.Values) (*Output, error) {
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/execution/%d/output", id), data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if err := checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	var output Output
+	if err := json.NewDecoder(res.Body).Decode(&output); err != nil {
+		return nil, err
+	}
+
+	return &output, nil
+}
+
+// pollOutput calls handle with each batch of output entries until the execution output is completed.
+// If fromStart is false, polling starts at the end of the log written so far.
+func (r *Rundeck) pollOutput(id int, fromStart bool, handle func([]Entry) error) error {
 	offset, lastmod := 0, 0
 	data := url.Values{}
-	var output Output
+
+	if !fromStart {
+		output, err := r.fetchOutput(id, url.Values{"offset": {"0"}, "maxlines": {"1"}})
+		if err != nil {
+			return err
+		}
+		offset = output.TotalSize
+	}
 
 	fn := func() (bool, error) {
 		data.Set("offset", strconv.Itoa(offset))
 		data.Set("lastmod", strconv.Itoa(lastmod))
 
-		res, err := r.request(http.MethodGet, fmt.Sprintf("/execution/%d/output", act.ID), data)
+		output, err := r.fetchOutput(id, data)
 		if err != nil {
 			return false, err
 		}
-		defer res.Body.Close()
 
-		if err := json.NewDecoder(res.Body).Decode(&output); err != nil {
+		if err := handle(output.Entries); err != nil {
 			return false, err
 		}
 
-		for _, e := range output.Entries {
-			fmt.Fprintln(r.out, e.Log)
-		}
-
 		if output.Completed {
 			return true, nil
 		}
 
 		offset, lastmod = output.Offset, output.LastModified
 
-		time.Sleep(1 * time.Second)
+		// wait for new output unless the last page had entries,
+		// even after the execution completes while its log is still being written
+		if len(output.Entries) == 0 {
+			time.Sleep(1 * time.Second)
+		}
 
 		return false, nil
 	}
@@ -271,33 +558,151 @@ func (r *Rundeck) tailActivity(act Act) error {
 	return nil
 }
 
-func (r *Rundeck) run(job string, opts []string) error {
-	if job == "" {
-		return fmt.Errorf("job required")
+// tailActivity prints the output of the execution until it completes.
+// If fromStart is false, the output already written before the first poll is skipped.
+func (r *Rundeck) tailActivity(act Act, fromStart bool) error {
+	return r.pollOutput(act.ID, fromStart, func(entries []Entry) error {
+		for _, e := range entries {
+			fmt.Fprintln(r.out, e.Log)
+		}
+		return nil
+	})
+}
+
+func (r *Rundeck) displayAct(act Act, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(r.out).Encode(act)
 	}
 
-	jobs, err := r.getJobs()
+	fmt.Fprintf(r.out, "%d %s\n", act.ID, act.Permalink)
+
+	return nil
+}
+
+// setRunFlags sets the node filter, the user and the loglevel of the flags to the data of a run.
+func setRunFlags(data url.Values, flags map[string]string) error {
+	if filter, ok := flags["filter"]; ok {
+		data.Set("filter", filter)
+	}
+	if user, ok := flags["as-user"]; ok {
+		data.Set("asUser", user)
+	}
+	if level, ok := flags["loglevel"]; ok {
+		level = strings.ToUpper(level)
+		if !contains(logLevels, level) {
+			return fmt.Errorf("invalid loglevel '%s' (allowed: %s)", flags["loglevel"], strings.Join(logLevels, ", "))
+		}
+		data.Set("loglevel", level)
+	}
+
+	return nil
+}
+
+// Run runs the job with the args of the run command.
+func (r *Rundeck) Run(ra RunArgs) error {
+	opts, flags := ra.Opts, ra.Flags
+
+	jb, err := r.findJob(ra.Job)
 	if err != nil {
 		return err
 	}
 
-	jb := jobs.pick(job)
-	if jb == nil {
-		return fmt.Errorf("job(%s) not found", job)
+	jobDef, err := r.fetchJobDefinition(*jb)
+	if err != nil {
+		return err
 	}
 
-	act, err := r.runJob(*jb, opts)
+	args, problems, err := jobDef.resolveOptions(opts, flags)
 	if err != nil {
 		return err
 	}
 
+	values := make(optionArgs, 0, len(ra.Values))
+	for _, opt := range jobDef.Opts {
+		if v, ok := ra.Values[opt.Name]; ok {
+			values = append(values, optionArg{name: opt.Name, value: v})
+		}
+	}
+	args = args.merge(values)
+
+	for _, opt := range jobDef.Opts {
+		switch {
+		case opt.ValuesErr != nil && opt.IsEnforced:
+			fmt.Fprintf(r.out, "warning: %s (values of '-%s' are not checked)\n", opt.ValuesErr, opt.Name)
+		case opt.ValuesErr != nil:
+			fmt.Fprintf(r.out, "warning: %s\n", opt.ValuesErr)
+		}
+	}
+
+	problems = append(problems, jobDef.validate(args)...)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid options for job(%s):\n\t%s", jb.Label, strings.Join(problems, "\n\t"))
+	}
+
+	data := url.Values{}
+	if r.dryRun {
+		// nothing is sent in dry-run mode, so the values of secret options can be hidden
+		args = jobDef.redactArgs(args)
+	}
+	data.Set("argString", args.argString())
+	if err := setRunFlags(data, flags); err != nil {
+		return err
+	}
+
+	if at, ok := flags["at"]; ok {
+		t, err := parseRunAt(at, time.Now())
+		if err != nil {
+			return err
+		}
+		data.Set("runAtTime", t.Format(runAtTimeFmt))
+	}
+
+	if flags["preview"] != "" {
+		filter := data.Get("filter")
+		if filter == "" {
+			filter = jobDef.NodeFilters.Filter
+		}
+		if filter == "" {
+			return fmt.Errorf("job(%s) has no node filter to preview", jb.Label)
+		}
+
+		ok, err := r.previewNodes(jb.Label, filter)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrCancelled
+		}
+	}
+
+	act, err := r.runJob(*jb, data)
+	if err == ErrDryRun {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if flags["detach"] != "" {
+		return r.displayAct(*act, flags["json"] != "")
+	}
+
+	if data.Get("runAtTime") != "" {
+		fmt.Fprintf(r.out, "execution %d is scheduled at %s (%s)\n", act.ID, data.Get("runAtTime"), act.Permalink)
+		return nil
+	}
+
+	return r.follow(*act)
+}
+
+// follow tails the started execution and reports its final status.
+func (r *Rundeck) follow(act Act) error {
 	fmt.Fprintf(r.out, "job is running (%s)\n", act.Permalink)
-	if err := r.tailActivity(*act); err != nil {
+	if err := r.tailActivity(act, true); err != nil {
 		return err
 	}
-	r.out.Write([]byte("done\n"))
 
-	return nil
+	return r.reportStatus(act.ID)
 }
 
 func (r *Rundeck) displayJob(jobDef JobDef) {
@@ -330,16 +735,43 @@ func (r *Rundeck) displayJobs(jobs []Job) {
 	}
 }
 
+// displayJobTree prints the labels of the jobs as a tree of their groups.
+func (r *Rundeck) displayJobTree(jobs []Job) {
+	labels := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		labels = append(labels, job.Label)
+	}
+	sort.Strings(labels)
+
+	fmt.Fprintln(r.out, "available jobs:")
+
+	var prev []string
+	for _, l := range labels {
+		segs := strings.Split(l, "/")
+		groups, name := segs[:len(segs)-1], segs[len(segs)-1]
+
+		n := 0
+		for n < len(groups) && n < len(prev) && groups[n] == prev[n] {
+			n++
+		}
+		for i := n; i < len(groups); i++ {
+			fmt.Fprintf(r.out, "%s%s/\n", strings.Repeat("\t", i+1), groups[i])
+		}
+		fmt.Fprintf(r.out, "%s%s\n", strings.Repeat("\t", len(groups)+1), name)
+
+		prev = groups
+	}
+}
+
 func (r *Rundeck) Do(cmd string, args []string) error {
 	switch cmd {
 	case CmdRun:
-		if len(args) < 1 {
-			return fmt.Errorf("job name required")
+		ra, err := ParseRunArgs(args)
+		if err != nil {
+			return err
 		}
 
-		job, opts := args[0], args[1:]
-
-		return r.run(job, opts)
+		return r.Run(*ra)
 	case CmdHelp:
 		if len(args) < 1 {
 			return fmt.Errorf("sub command required")
@@ -348,11 +780,24 @@ func (r *Rundeck) Do(cmd string, args []string) error {
 		subCmd, opts := args[0], args[1:]
 		switch subCmd {
 		case SubCmdJobs:
+			flags, rest, err := parseFlags(opts, map[string]bool{"tree": false})
+			if err != nil {
+				return err
+			}
+
 			jobs, err := r.getJobs()
 			if err != nil {
 				return err
 			}
 
+			if len(rest) > 0 {
+				jobs = jobs.filter(rest[0])
+			}
+
+			if flags["tree"] != "" {
+				r.displayJobTree(jobs)
+				break
+			}
 			r.displayJobs(jobs)
 		case SubCmdJob:
 			if len(opts) < 1 {
@@ -369,6 +814,20 @@ func (r *Rundeck) Do(cmd string, args []string) error {
 		default:
 			return fmt.Errorf("sub command '%s' not found", subCmd)
 		}
+	case CmdExecutions:
+		return r.executions(args)
+	case CmdAbort:
+		return r.abort(args)
+	case CmdTail:
+		return r.tail(args)
+	case CmdRetry:
+		return r.retry(args)
+	case CmdState:
+		return r.state(args)
+	case CmdOutput:
+		return r.output(args)
+	case CmdExport:
+		return r.export(args)
 	default:
 		return fmt.Errorf("command '%s' not found", cmd)
 	}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -37,7 +39,7 @@ func TestGetJobLabels(t *testing.T) {
 ]`
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		expectURL := fmt.Sprintf("/api/16/project/%s/jobs", testProject)
+		expectURL := fmt.Sprintf("/api/18/project/%s/jobs", testProject)
 
 		if r.Method != http.MethodGet {
 			t.Error("http method should be GET")
@@ -80,7 +82,7 @@ func TestDo(t *testing.T) {
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
-		case fmt.Sprintf("/api/16/project/%s/jobs", testProject):
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
 			testRes := `[
   {
     "id": "test-id-0",
@@ -106,7 +108,7 @@ func TestDo(t *testing.T) {
 			}
 
 			w.Write([]byte(testRes))
-		case "/api/16/job/test-id-0":
+		case "/api/18/job/test-id-0":
 			testRes := `- description: 'deploy'
   executionEnabled: true
   id: test-id-0
@@ -126,7 +128,7 @@ func TestDo(t *testing.T) {
 			}
 
 			w.Write([]byte(testRes))
-		case "/api/16/job/test-id-0/executions":
+		case "/api/18/job/test-id-0/executions":
 			testRes := `{
   "id": 0,
   "href": "",
@@ -157,7 +159,32 @@ func TestDo(t *testing.T) {
 			}
 
 			w.Write([]byte(testRes))
-		case "/api/16/execution/0/output":
+		case "/api/18/execution/0":
+			testRes := `{
+  "id": 0,
+  "href": "",
+  "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/0",
+  "status": "succeeded",
+  "project": "test-rundeck",
+  "user": "admin",
+  "date-started": {
+    "unixtime": 1477980000,
+    "date": "2016-11-01T15:00:00Z"
+  },
+  "date-ended": {
+    "unixtime": 1477980001,
+    "date": "2016-11-01T15:00:01Z"
+  },
+  "description": "touch deploy.lock [... 2 steps]",
+  "argstring": null
+}`
+
+			if r.Method != http.MethodGet {
+				t.Error("http method should be GET")
+			}
+
+			w.Write([]byte(testRes))
+		case "/api/18/execution/0/output":
 			// refs: http://rundeck.org/2.6.4/api/index.html#output-content
 			var testRes string
 			if outputAPICount == 0 {
@@ -263,6 +290,22 @@ func TestDo(t *testing.T) {
 			t.Errorf("error message not match. got:%s, expect:%s", err.Error(), "job name required")
 		}
 
+		err = rd.Do(CmdRun, []string{"deploy", "--json"})
+		if err == nil {
+			t.Error("should return error message")
+		}
+		if err.Error() != "flag '--json' requires '--detach'" {
+			t.Errorf("error message not match. got:%s, expect:%s", err.Error(), "flag '--json' requires '--detach'")
+		}
+
+		err = rd.Do(CmdRun, []string{"deploy", "-evn", "prod"})
+		if err == nil {
+			t.Error("should return error message")
+		}
+		if err.Error() != "invalid options for job(deploy):\n\tunknown option '-evn'" {
+			t.Errorf("error message not match. got:%s, expect:%s", err.Error(), "invalid options for job(deploy):\n\tunknown option '-evn'")
+		}
+
 		err = rd.Do(CmdHelp, []string{})
 		if err == nil {
 			t.Error("should return error message")
@@ -300,6 +343,42 @@ func TestDo(t *testing.T) {
 		}
 	})
 
+	t.Run("help jobs filter", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdHelp, []string{SubCmdJobs, "dpl"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(`available jobs:
+
+	 deploy
+		 deploy
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("job not found", func(t *testing.T) {
+		err := rd.Do(CmdRun, []string{"deplyo"})
+
+		nf, ok := err.(*JobNotFoundError)
+		if !ok {
+			t.Fatalf("error should be JobNotFoundError. got:%v", err)
+		}
+
+		expectSuggestions := []string{"deploy"}
+		if !reflect.DeepEqual(nf.Suggestions, expectSuggestions) {
+			t.Errorf("suggestions not match. got:%v, expect:%v", nf.Suggestions, expectSuggestions)
+		}
+
+		expectErr := "job(deplyo) not found, did you mean:\n\tdeploy"
+		if err.Error() != expectErr {
+			t.Errorf("error message not match. got:%s, expect:%s", err.Error(), expectErr)
+		}
+	})
+
 	t.Run("help job", func(t *testing.T) {
 		var w bytes.Buffer
 		rd.out = &w
@@ -327,7 +406,47 @@ func TestDo(t *testing.T) {
 		expectOut := []byte(`job is running (http://test.rundeck.in/project/test-rundeck/execution/show/0)
 test-log-1
 test-log-2
-done
+done (succeeded)
+`)
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("run job detached", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--detach"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("0 http://test.rundeck.in/project/test-rundeck/execution/show/0\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("run job at", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--at", "2099-01-02T03:04:05Z"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte("execution 0 is scheduled at 2099-01-02T03:04:05+0000 (http://test.rundeck.in/project/test-rundeck/execution/show/0)\n")
+		if !bytes.Equal(w.Bytes(), expectOut) {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
+		}
+	})
+
+	t.Run("run job detached json", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--detach", "--json"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := []byte(`{"id":0,"permalink":"http://test.rundeck.in/project/test-rundeck/execution/show/0"}
 `)
 		if !bytes.Equal(w.Bytes(), expectOut) {
 			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), string(expectOut))
@@ -369,7 +488,7 @@ func TestAuthWithPass(t *testing.T) {
 
 	// refs: http://rundeck.org/2.6.4/api/index.html#password-authentication
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		expectURL := "/api/16/j_security_check"
+		expectURL := "/api/18/j_security_check"
 
 		r.ParseForm()
 		values := r.PostForm
@@ -402,3 +521,416 @@ func TestAuthWithPass(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRunAsUser(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	var posted url.Values
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[{"id": "test-id-0", "name": "deploy", "group": null, "project": "test-rundeck", "description": "deploy"}]`))
+		case "/api/18/job/test-id-0":
+			w.Write([]byte("- name: deploy\n  description: deploy\n"))
+		case "/api/18/job/test-id-0/executions":
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if posted, err = url.ParseQuery(string(b)); err != nil {
+				t.Error(err)
+			}
+
+			if posted.Get("asUser") == "root" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{
+  "error": true,
+  "apiversion": 18,
+  "errorCode": "api.error.item.unauthorized",
+  "message": "Not authorized for action \"Run as User\" for Job ID test-id-0"
+}`))
+				break
+			}
+
+			w.Write([]byte(`{"id": 40, "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/40"}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("as user and loglevel", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--as-user", "deployer", "--loglevel", "debug", "--detach"}); err != nil {
+			t.Error(err)
+		}
+
+		expectPosted := url.Values{"argString": {""}, "asUser": {"deployer"}, "loglevel": {"DEBUG"}}
+		if !reflect.DeepEqual(posted, expectPosted) {
+			t.Errorf("posted data not match. got:%v, expect:%v", posted, expectPosted)
+		}
+	})
+
+	t.Run("invalid loglevel", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		err := rd.Do(CmdRun, []string{"deploy", "--loglevel", "trace"})
+
+		expectErr := "invalid loglevel 'trace' (allowed: DEBUG, VERBOSE, INFO, WARN, ERROR)"
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("not authorized", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		err := rd.Do(CmdRun, []string{"deploy", "--as-user", "root"})
+
+		expectErr := `not authorized: Not authorized for action "Run as User" for Job ID test-id-0`
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+		if _, ok := err.(*APIError); !ok {
+			t.Errorf("error should be *APIError. got:%T", err)
+		}
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("http method should be GET")
+		}
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[{"id": "test-id-0", "name": "deploy", "group": null, "project": "test-rundeck", "description": "deploy"}]`))
+		case "/api/18/job/test-id-0":
+			w.Write([]byte(`- name: deploy
+  description: deploy
+  options:
+  - name: env
+    required: true
+  - name: password
+    secure: true
+`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	rd.SetDryRun(true)
+
+	t.Run("run", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "-env", "prod", "-password", "p@ss", "--filter", "tags: web"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := fmt.Sprintf(`dry-run: POST %s/api/18/job/test-id-0/executions
+	argString=-env prod -password ****
+	filter=tags: web
+`, ts.URL)
+		if w.String() != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectOut)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		err := rd.Do(CmdRun, []string{"deploy", "-password", "p@ss"})
+
+		expectErr := "invalid options for job(deploy):\n\tmissing required option '-env'"
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("secret values like options", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"deploy", "-env", "prod", "-password", "-Xyz123"},
+			{"deploy", "-password", "--Xyz123", "-env", "prod"},
+		} {
+			var w bytes.Buffer
+			rd.out = &w
+			if err := rd.Do(CmdRun, args); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(w.String(), "Xyz123") {
+				t.Errorf("secret value should be redacted. got:\n%s", w.String())
+			}
+		}
+
+		err := rd.Do(CmdRun, []string{"deploy", "-password", "-Xyz123"})
+		expectErr := "invalid options for job(deploy):\n\tmissing required option '-env'"
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("values", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		ra := RunArgs{Job: "deploy", Opts: []string{"-password", "p@ss"}, Values: map[string]string{"env": "--prod x"}}
+		if err := rd.Run(ra); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := fmt.Sprintf(`dry-run: POST %s/api/18/job/test-id-0/executions
+	argString=-password **** -env "--prod x"
+`, ts.URL)
+		if w.String() != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectOut)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdAbort, []string{"3"}); err != nil {
+			t.Error(err)
+		}
+
+		expectOut := fmt.Sprintf("dry-run: POST %s/api/18/execution/3/abort\n", ts.URL)
+		if w.String() != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectOut)
+		}
+	})
+}
+
+func TestJobGroups(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	testRes := `[
+  {"id": "test-id-0", "name": "deploy", "group": "web", "project": "test-rundeck", "description": ""},
+  {"id": "test-id-1", "name": "deploy", "group": "db", "project": "test-rundeck", "description": ""},
+  {"id": "test-id-2", "name": "Restart App", "group": "web/App Servers", "project": "test-rundeck", "description": ""},
+  {"id": "test-id-3", "name": "cleanup", "group": null, "project": "test-rundeck", "description": ""}
+]`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRes))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		labels, err := rd.GetJobLabels()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectLabels := []string{"web/deploy", "db/deploy", "web/app-servers/restart-app", "cleanup"}
+		if !reflect.DeepEqual(labels, expectLabels) {
+			t.Errorf("labels not match. got:%v, expect:%v", labels, expectLabels)
+		}
+	})
+
+	t.Run("help jobs --tree", func(t *testing.T) {
+		buf.Reset()
+		if err := rd.Do(CmdHelp, []string{SubCmdJobs, "--tree"}); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := "available jobs:\n" +
+			"\tcleanup\n" +
+			"\tdb/\n" +
+			"\t\tdeploy\n" +
+			"\tweb/\n" +
+			"\t\tapp-servers/\n" +
+			"\t\t\trestart-app\n" +
+			"\t\tdeploy\n"
+		if buf.String() != expect {
+			t.Errorf("output not match. got:%q, expect:%q", buf.String(), expect)
+		}
+	})
+}
+
+func TestJobCollisions(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+
+	testJobs := `[
+  {"id": "1a2b3c4d-0000", "name": "Deploy App", "group": null, "project": "test-rundeck", "description": ""},
+  {"id": "5e6f7a8b-0000", "name": "deploy_app", "group": null, "project": "test-rundeck", "description": ""},
+  {"id": "9c0d1e2f-0000", "name": "cleanup", "group": null, "project": "test-rundeck", "description": ""}
+]`
+	testJobDef := `- name: deploy_app
+  description: deploy app
+`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(testJobs))
+		case "/api/18/job/5e6f7a8b-0000":
+			w.Write([]byte(testJobDef))
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		labels, err := rd.GetJobLabels()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectLabels := []string{"deploy-app@1a2b3c4d", "deploy-app@5e6f7a8b", "cleanup"}
+		if !reflect.DeepEqual(labels, expectLabels) {
+			t.Errorf("labels not match. got:%v, expect:%v", labels, expectLabels)
+		}
+	})
+
+	t.Run("ambiguous label", func(t *testing.T) {
+		expectErr := "job(deploy-app) is ambiguous, candidates:\n\tdeploy-app@1a2b3c4d\n\tdeploy-app@5e6f7a8b"
+
+		err := rd.Do(CmdHelp, []string{SubCmdJob, "deploy-app"})
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error not match. got:%v, expect:%s", err, expectErr)
+		}
+
+		err = rd.Do(CmdRun, []string{"deploy-app"})
+		if err == nil || err.Error() != expectErr {
+			t.Errorf("error not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("suffixed label", func(t *testing.T) {
+		jobDef, err := rd.GetJobDefinition("deploy-app@5e6f7a8b")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if jobDef.Label != "deploy-app@5e6f7a8b" {
+			t.Errorf("label not match. got:%s", jobDef.Label)
+		}
+	})
+}
+
+func TestJobByID(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	testID := "3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d"
+
+	testJobInfo := `{"id": "3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d", "name": "deploy", "group": "web", "project": "test-rundeck", "description": "deploy web"}`
+	testJobDef := `- name: deploy
+  group: web
+  description: deploy web
+`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/18/job/" + testID + "/info":
+			w.Write([]byte(testJobInfo))
+		case "/api/18/job/" + testID:
+			w.Write([]byte(testJobDef))
+		case "/api/18/job/unknown/info":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range []string{testID, ts.URL + "/project/test-rundeck/job/show/" + testID} {
+		jobDef, err := rd.GetJobDefinition(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if jobDef.Label != "web/deploy" {
+			t.Errorf("label not match. got:%s, expect:%s", jobDef.Label, "web/deploy")
+		}
+	}
+
+	expectErr := "job(unknown) not found"
+	if _, err := rd.GetJobDefinition(ts.URL + "/job/show/unknown"); err == nil || err.Error() != expectErr {
+		t.Errorf("error not match. got:%v, expect:%s", err, expectErr)
+	}
+}
+
+func TestParseRunArgs(t *testing.T) {
+	ra, err := ParseRunArgs([]string{"--detach", "--options-file", "p.yaml", "deploy", "-env", "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &RunArgs{
+		Job:   "deploy",
+		Opts:  []string{"-env", "prod"},
+		Flags: map[string]string{"detach": "true", "options-file": "p.yaml"},
+	}
+	if !reflect.DeepEqual(ra, expect) {
+		t.Errorf("run args not match. got:%+v, expect:%+v", ra, expect)
+	}
+
+	if _, err := ParseRunArgs([]string{"--detach"}); err == nil || err.Error() != "job name required" {
+		t.Errorf("error message not match. got:%v, expect:%s", err, "job name required")
+	}
+}
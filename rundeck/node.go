@@ -0,0 +1,77 @@
+package rundeck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"text/tabwriter"
+)
+
+type Node struct {
+	Name     string `json:"nodename"`
+	Hostname string `json:"hostname"`
+	Tags     string `json:"tags"`
+}
+
+type Nodes []Node
+
+func (r *Rundeck) getNodes(filter string) (Nodes, error) {
+	data := url.Values{}
+	data.Set("format", "json")
+	data.Set("filter", filter)
+	res, err := r.request(http.MethodGet, fmt.Sprintf("/project/%s/resources", r.project), data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var m map[string]Node
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	nodes := make(Nodes, 0, len(m))
+	for name, n := range m {
+		if n.Name == "" {
+			n.Name = name
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	return nodes, nil
+}
+
+func (r *Rundeck) displayNodes(nodes Nodes) {
+	w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tHOSTNAME\tTAGS")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", n.Name, n.Hostname, n.Tags)
+	}
+	w.Flush()
+}
+
+// previewNodes lists the nodes matching the filter and asks whether to run the job on them.
+// Without a confirm function set, it only lists the nodes and reports false.
+func (r *Rundeck) previewNodes(job, filter string) (bool, error) {
+	nodes, err := r.getNodes(filter)
+	if err != nil {
+		return false, err
+	}
+
+	if len(nodes) == 0 {
+		fmt.Fprintf(r.out, "no nodes match filter '%s'\n", filter)
+		return false, nil
+	}
+
+	fmt.Fprintf(r.out, "%d nodes match filter '%s'\n", len(nodes), filter)
+	r.displayNodes(nodes)
+
+	if r.confirm == nil {
+		return false, nil
+	}
+
+	return r.confirm(fmt.Sprintf("run job(%s) on these nodes?", job)), nil
+}
@@ -0,0 +1,143 @@
+package rundeck
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRunFilter(t *testing.T) {
+	testToken := "token"
+	testProject := "test-rundeck"
+	var posted url.Values
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/18/project/%s/jobs", testProject):
+			w.Write([]byte(`[{"id": "test-id-0", "name": "deploy", "group": null, "project": "test-rundeck", "description": "deploy"}]`))
+		case "/api/18/job/test-id-0":
+			w.Write([]byte(`- name: deploy
+  description: deploy
+  nodefilters:
+    filter: 'tags: web'
+`))
+		case fmt.Sprintf("/api/18/project/%s/resources", testProject):
+			filter := r.URL.Query().Get("filter")
+			if filter != "tags: web" {
+				w.Write([]byte(`{}`))
+				break
+			}
+
+			w.Write([]byte(`{
+  "web2": {"nodename": "web2", "hostname": "web2.rundeck.in", "tags": "web"},
+  "web1": {"nodename": "web1", "hostname": "web1.rundeck.in", "tags": "web"}
+}`))
+		case "/api/18/job/test-id-0/executions":
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if posted, err = url.ParseQuery(string(b)); err != nil {
+				t.Error(err)
+			}
+
+			w.Write([]byte(`{"id": 30, "permalink": "http://test.rundeck.in/project/test-rundeck/execution/show/30"}`))
+		default:
+			t.Errorf("request url is wrong. url:%s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rd, err := AuthWithToken(testToken, u.Scheme, u.Host, testProject, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectNodes := `2 nodes match filter 'tags: web'
+NODE  HOSTNAME         TAGS
+web1  web1.rundeck.in  web
+web2  web2.rundeck.in  web
+`
+
+	t.Run("filter", func(t *testing.T) {
+		posted = nil
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--filter", "name: web1", "--detach"}); err != nil {
+			t.Error(err)
+		}
+
+		if filter := posted.Get("filter"); filter != "name: web1" {
+			t.Errorf("filter not match. got:%s, expect:%s", filter, "name: web1")
+		}
+	})
+
+	t.Run("preview without confirm", func(t *testing.T) {
+		posted = nil
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--preview", "--detach"}); err != ErrCancelled {
+			t.Errorf("error not match. got:%v, expect:%v", err, ErrCancelled)
+		}
+
+		if posted != nil {
+			t.Error("job should not run")
+		}
+		if w.String() != expectNodes {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectNodes)
+		}
+	})
+
+	t.Run("preview confirmed", func(t *testing.T) {
+		posted = nil
+		var w bytes.Buffer
+		rd.out = &w
+		var asked string
+		rd.SetConfirm(func(msg string) bool {
+			asked = msg
+			return true
+		})
+		defer rd.SetConfirm(nil)
+
+		if err := rd.Do(CmdRun, []string{"deploy", "--filter", "tags: web", "--preview", "--detach"}); err != nil {
+			t.Error(err)
+		}
+
+		if asked != "run job(deploy) on these nodes?" {
+			t.Errorf("confirm message not match. got:%s", asked)
+		}
+		if posted == nil {
+			t.Error("job should run")
+		}
+
+		expectOut := expectNodes + "30 http://test.rundeck.in/project/test-rundeck/execution/show/30\n"
+		if w.String() != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectOut)
+		}
+	})
+
+	t.Run("preview no nodes", func(t *testing.T) {
+		posted = nil
+		var w bytes.Buffer
+		rd.out = &w
+		if err := rd.Do(CmdRun, []string{"deploy", "--filter", "tags: db", "--preview"}); err != ErrCancelled {
+			t.Errorf("error not match. got:%v, expect:%v", err, ErrCancelled)
+		}
+
+		if posted != nil {
+			t.Error("job should not run")
+		}
+		if expectOut := "no nodes match filter 'tags: db'\n"; w.String() != expectOut {
+			t.Errorf("output not match.\ngot:\n%s\nexpect:\n%s", w.String(), expectOut)
+		}
+	})
+}
@@ -0,0 +1,554 @@
+package rundeck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	redacted = "****"
+
+	valuesURLTimeout = 5 * time.Second
+)
+
+var (
+	reOptName = regexp.MustCompile(`^-[^-0-9.]`)
+	reEnvVar  = regexp.MustCompile(`\$?\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+)
+
+// optionArg is a "-name value" pair given to the run command.
+type optionArg struct {
+	name  string
+	value string
+}
+
+type optionArgs []optionArg
+
+// argString builds the argString parameter of the run job API.
+func (oas optionArgs) argString() string {
+	list := make([]string, 0, len(oas))
+	for _, oa := range oas {
+		// Rundeck splits the argString at blanks and treats both kinds of quotes as quoting,
+		// and an empty value left unquoted would pair the option with the next one
+		value := oa.value
+		if value == "" || strings.ContainsAny(value, " \t\"'") {
+			value = `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+		}
+		list = append(list, "-"+oa.name+" "+value)
+	}
+
+	return strings.Join(list, " ")
+}
+
+// parseOptionArgs parses "-name value" pairs. The arg after an option name is always its value,
+// even if it looks like an option name, and a value may span several args until the next option name.
+// It returns the problems found along with the pairs; values are never echoed in them.
+func parseOptionArgs(args []string) (optionArgs, []string) {
+	oas := make(optionArgs, 0, len(args)/2)
+	problems := make([]string, 0)
+
+	values := make([]string, 0, 1)
+	flush := func() {
+		if len(oas) == 0 {
+			return
+		}
+
+		last := &oas[len(oas)-1]
+		if len(values) == 0 {
+			problems = append(problems, fmt.Sprintf("option '-%s' requires a value", last.name))
+		}
+		last.value = strings.Join(values, " ")
+		values = values[:0]
+	}
+
+	for _, arg := range args {
+		expectValue := len(oas) > 0 && len(values) == 0
+		if reOptName.MatchString(arg) && !expectValue {
+			flush()
+			oas = append(oas, optionArg{name: arg[1:]})
+			continue
+		}
+
+		if len(oas) == 0 {
+			problems = append(problems, fmt.Sprintf("unexpected argument '%s'", arg))
+			continue
+		}
+		values = append(values, arg)
+	}
+	flush()
+
+	return oas, problems
+}
+
+// remoteValue is an element of the JSON list served by a valuesUrl.
+type remoteValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// parseRemoteValues decodes the value list served by a valuesUrl, which is either
+// a list of strings, a list of name/value objects or an object mapping names to values.
+func parseRemoteValues(b []byte) ([]string, error) {
+	var strs []string
+	if err := json.Unmarshal(b, &strs); err == nil {
+		return strs, nil
+	}
+
+	var rvs []remoteValue
+	if err := json.Unmarshal(b, &rvs); err == nil {
+		values := make([]string, 0, len(rvs))
+		for _, rv := range rvs {
+			values = append(values, rv.Value)
+		}
+		return values, nil
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unsupported value list")
+	}
+
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	return values, nil
+}
+
+// optionValues is the result of fetching a valuesUrl, cached whether it failed or not.
+type optionValues struct {
+	values []string
+	err    error
+}
+
+// OptionValues returns the allowed values of the option. Values of a valuesUrl are
+// fetched with a timeout and cached per URL along with failures; otherwise the values
+// of the definition are returned.
+func (r *Rundeck) OptionValues(opt JobOption) ([]string, error) {
+	if opt.ValuesURL == "" {
+		return opt.Values, nil
+	}
+
+	r.valuesMu.Lock()
+	cached, ok := r.valuesCache[opt.ValuesURL]
+	r.valuesMu.Unlock()
+
+	if !ok {
+		values, err := fetchOptionValues(opt.ValuesURL)
+		cached = optionValues{values: values, err: err}
+
+		r.valuesMu.Lock()
+		if r.valuesCache == nil {
+			r.valuesCache = make(map[string]optionValues)
+		}
+		r.valuesCache[opt.ValuesURL] = cached
+		r.valuesMu.Unlock()
+	}
+
+	if cached.err != nil {
+		return nil, fmt.Errorf("failed to fetch values of option '-%s': %s", opt.Name, cached.err)
+	}
+
+	return cached.values, nil
+}
+
+func fetchOptionValues(valuesURL string) ([]string, error) {
+	client := &http.Client{Timeout: valuesURLTimeout}
+	res, err := client.Get(valuesURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", res.Status)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRemoteValues(b)
+}
+
+// fetchValues fills the values of the options having a valuesUrl, fetching them at once.
+// A failure is kept in ValuesErr of the option, whose values are then unknown.
+func (jd *JobDef) fetchValues(r *Rundeck) {
+	var wg sync.WaitGroup
+	for i := range jd.Opts {
+		if jd.Opts[i].ValuesURL == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(opt *JobOption) {
+			defer wg.Done()
+
+			values, err := r.OptionValues(*opt)
+			if err != nil {
+				opt.ValuesErr = err
+				return
+			}
+			opt.Values = values
+		}(&jd.Opts[i])
+	}
+	wg.Wait()
+}
+
+// merge returns the args of oas that are not overridden by others, followed by others.
+func (oas optionArgs) merge(others optionArgs) optionArgs {
+	overridden := make(map[string]bool, len(others))
+	for _, oa := range others {
+		overridden[oa.name] = true
+	}
+
+	merged := make(optionArgs, 0, len(oas)+len(others))
+	for _, oa := range oas {
+		if !overridden[oa.name] {
+			merged = append(merged, oa)
+		}
+	}
+
+	return append(merged, others...)
+}
+
+// optionValue is the value of an option in an options file, either a scalar or a list.
+// Values are kept as written in the file, so that "NO" or "0123" are not turned into other values.
+type optionValue struct {
+	scalar  string
+	list    []string
+	isList  bool
+	invalid bool
+}
+
+func (ov *optionValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&ov.scalar); err == nil {
+		return nil
+	}
+
+	if err := unmarshal(&ov.list); err == nil {
+		ov.isList = true
+		return nil
+	}
+
+	ov.invalid = true
+	return nil
+}
+
+// jsonText returns the text of a JSON scalar, unquoting strings and keeping numbers as written.
+func jsonText(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+
+	switch t := string(bytes.TrimSpace(raw)); {
+	case t == "null":
+		return "", true
+	case strings.HasPrefix(t, "{"), strings.HasPrefix(t, "["):
+		return "", false
+	default:
+		return t, true
+	}
+}
+
+func (ov *optionValue) UnmarshalJSON(b []byte) error {
+	if s, ok := jsonText(b); ok {
+		ov.scalar = s
+		return nil
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		ov.invalid = true
+		return nil
+	}
+
+	ov.isList = true
+	for _, raw := range raws {
+		s, ok := jsonText(raw)
+		if !ok {
+			ov.invalid = true
+			return nil
+		}
+		ov.list = append(ov.list, s)
+	}
+
+	return nil
+}
+
+// optionItem is an option name and its value in an options file.
+type optionItem struct {
+	name  string
+	value optionValue
+}
+
+// readOptionsFile reads the option values of a YAML or JSON file, keeping the order of the file.
+func readOptionsFile(filename string) ([]optionItem, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]optionValue
+	var names []string
+
+	if filepath.Ext(filename) != ".json" {
+		// the values are decoded into strings to keep them as written,
+		// and the order of the names is taken from a second decoding
+		var keys yaml.MapSlice
+		if err := yaml.Unmarshal(b, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse options file(%s): %s", filename, err)
+		}
+		if err := yaml.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse options file(%s): %s", filename, err)
+		}
+
+		for _, k := range keys {
+			names = append(names, fmt.Sprint(k.Key))
+		}
+	} else {
+		if err := json.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse options file(%s): %s", filename, err)
+		}
+
+		// JSON objects are unordered, so the options are sorted by name
+		for k := range values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+	}
+
+	items := make([]optionItem, 0, len(names))
+	for _, name := range names {
+		items = append(items, optionItem{name: name, value: values[name]})
+	}
+
+	return items, nil
+}
+
+// expandEnv expands "${VAR}" to the value of the environment variable VAR.
+// "$${VAR}" is kept as "${VAR}", and any other "$" is kept as it is.
+func expandEnv(s string) string {
+	return reEnvVar.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+		return os.Getenv(m[2 : len(m)-1])
+	})
+}
+
+// loadOptionsFile loads the option args from a YAML or JSON file mapping option names to values.
+// Lists are joined with the delimiter of the option, and "${VAR}" in values is expanded.
+func (jd JobDef) loadOptionsFile(filename string) (optionArgs, error) {
+	items, err := readOptionsFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	oas := make(optionArgs, 0, len(items))
+	for _, item := range items {
+		v := item.value
+		if v.invalid {
+			return nil, fmt.Errorf("option '%s' in options file(%s) must be a value or a list", item.name, filename)
+		}
+
+		value := expandEnv(v.scalar)
+		if v.isList {
+			delim := ","
+			if opt := jd.Option(item.name); opt != nil && opt.Delimiter != "" {
+				delim = opt.Delimiter
+			}
+
+			list := make([]string, 0, len(v.list))
+			for _, e := range v.list {
+				list = append(list, expandEnv(e))
+			}
+			value = strings.Join(list, delim)
+		}
+
+		oas = append(oas, optionArg{name: item.name, value: value})
+	}
+
+	return oas, nil
+}
+
+// resolveOptions builds the option args of a run from the command line args and the
+// "--options-file" flag. Values given on the command line override the values in the file.
+func (jd JobDef) resolveOptions(opts []string, flags map[string]string) (optionArgs, []string, error) {
+	oas, problems := parseOptionArgs(opts)
+
+	filename, ok := flags["options-file"]
+	if !ok {
+		return oas, problems, nil
+	}
+
+	fileArgs, err := jd.loadOptionsFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fileArgs.merge(oas), problems, nil
+}
+
+// Option returns the option of the job definition named name, or nil if there is none.
+func (jd JobDef) Option(name string) *JobOption {
+	for i := range jd.Opts {
+		if jd.Opts[i].Name == name {
+			return &jd.Opts[i]
+		}
+	}
+	return nil
+}
+
+// Secret reports whether the value of the option must not be echoed,
+// which is the case for both secure and secureExposed options.
+func (opt JobOption) Secret() bool {
+	return opt.IsSecure || opt.IsExposed
+}
+
+// Allows reports whether value is accepted by the option.
+// Options that are not enforced, or whose values are unknown, accept any value.
+func (opt JobOption) Allows(value string) bool {
+	if !opt.IsEnforced || len(opt.Values) == 0 {
+		return true
+	}
+
+	values := []string{value}
+	if opt.IsMultiValued {
+		delim := opt.Delimiter
+		if delim == "" {
+			delim = ","
+		}
+		values = strings.Split(value, delim)
+	}
+
+	for _, v := range values {
+		if !contains(opt.Values, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MissingOptions returns the required options that are neither given in the run args,
+// including an options file, nor have a default value.
+func (jd JobDef) MissingOptions(args []string) []JobOption {
+	flags, rest, _ := parseFlags(args, runFlags)
+	oas, _, err := jd.resolveOptions(rest, flags)
+	if err != nil {
+		oas, _ = parseOptionArgs(rest)
+	}
+
+	given := make(map[string]bool, len(oas))
+	for _, oa := range oas {
+		given[oa.name] = true
+	}
+
+	missing := make([]JobOption, 0)
+	for _, opt := range jd.Opts {
+		if opt.IsRequired && opt.Value == "" && !given[opt.Name] {
+			missing = append(missing, opt)
+		}
+	}
+
+	return missing
+}
+
+// Redact returns a copy of args with the values of secret options replaced,
+// so that the args can be kept in history or logs.
+func (jd JobDef) Redact(args []string) []string {
+	list := make([]string, 0, len(args))
+	secret, afterName := false, false
+
+	for _, arg := range args {
+		switch {
+		case afterName:
+			// the arg after an option name is its value, whatever it looks like
+			afterName = false
+			if secret {
+				list = append(list, redacted)
+				continue
+			}
+		case strings.HasPrefix(arg, "--"):
+			secret = false
+		case reOptName.MatchString(arg):
+			opt := jd.Option(arg[1:])
+			secret, afterName = opt != nil && opt.Secret(), true
+		case secret:
+			if list[len(list)-1] != redacted {
+				list = append(list, redacted)
+			}
+			continue
+		}
+		list = append(list, arg)
+	}
+
+	return list
+}
+
+// redactArgs returns a copy of oas with the values of secret options replaced.
+func (jd JobDef) redactArgs(oas optionArgs) optionArgs {
+	redactedArgs := make(optionArgs, 0, len(oas))
+	for _, oa := range oas {
+		if opt := jd.Option(oa.name); opt != nil && opt.Secret() {
+			oa.value = redacted
+		}
+		redactedArgs = append(redactedArgs, oa)
+	}
+
+	return redactedArgs
+}
+
+// validate checks the option args against the options of the job definition
+// and returns every problem found.
+func (jd JobDef) validate(oas optionArgs) []string {
+	problems := make([]string, 0)
+	seen := make(map[string]bool, len(oas))
+
+	for _, oa := range oas {
+		opt := jd.Option(oa.name)
+		if opt == nil {
+			problems = append(problems, fmt.Sprintf("unknown option '-%s'", oa.name))
+			continue
+		}
+
+		if oa.value != "" && !opt.Allows(oa.value) {
+			value := oa.value
+			if opt.Secret() {
+				value = redacted
+			}
+			problems = append(problems, fmt.Sprintf("option '-%s' does not allow '%s' (allowed: %s)",
+				oa.name, value, strings.Join(opt.Values, ", ")))
+		}
+
+		if seen[oa.name] {
+			problems = append(problems, fmt.Sprintf("duplicate option '-%s'", oa.name))
+		}
+		seen[oa.name] = true
+	}
+
+	for _, opt := range jd.Opts {
+		if opt.IsRequired && opt.Value == "" && !seen[opt.Name] {
+			problems = append(problems, fmt.Sprintf("missing required option '-%s'", opt.Name))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,434 @@
+package rundeck
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseOptionArgs(t *testing.T) {
+	oas, problems := parseOptionArgs([]string{"-env", "prod", "-message", "hello", "world", "-count", "-1"})
+	if len(problems) > 0 {
+		t.Errorf("problems should be empty. got:%v", problems)
+	}
+
+	expectArgs := optionArgs{
+		{name: "env", value: "prod"},
+		{name: "message", value: "hello world"},
+		{name: "count", value: "-1"},
+	}
+	if !reflect.DeepEqual(oas, expectArgs) {
+		t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+	}
+
+	expectArgString := `-env prod -message "hello world" -count -1`
+	if s := oas.argString(); s != expectArgString {
+		t.Errorf("argString not match. got:%s, expect:%s", s, expectArgString)
+	}
+
+	oas, problems = parseOptionArgs([]string{"-password", "-Xyz123", "-env", "prod"})
+	if len(problems) > 0 {
+		t.Errorf("problems should be empty. got:%v", problems)
+	}
+	expectArgs = optionArgs{
+		{name: "password", value: "-Xyz123"},
+		{name: "env", value: "prod"},
+	}
+	if !reflect.DeepEqual(oas, expectArgs) {
+		t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+	}
+
+	_, problems = parseOptionArgs([]string{"prod", "-env"})
+	expectProblems := []string{
+		"unexpected argument 'prod'",
+		"option '-env' requires a value",
+	}
+	if !reflect.DeepEqual(problems, expectProblems) {
+		t.Errorf("problems not match. got:%v, expect:%v", problems, expectProblems)
+	}
+}
+
+func TestArgString(t *testing.T) {
+	tests := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"-a", "", "-b", "x"}, `-a "" -b x`},
+		{[]string{"-name", "O'Brien"}, `-name "O'Brien"`},
+		{[]string{"-message", `say "hi"`}, `-message "say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		oas, problems := parseOptionArgs(tt.args)
+		if len(problems) > 0 {
+			t.Errorf("problems should be empty. got:%v", problems)
+		}
+		if s := oas.argString(); s != tt.expect {
+			t.Errorf("argString not match. got:%s, expect:%s", s, tt.expect)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	jobDef := JobDef{
+		Name: "deploy",
+		Opts: []JobOption{
+			{Name: "env", IsRequired: true},
+			{Name: "branch", IsRequired: true},
+			{Name: "region", IsRequired: true, Value: "tokyo", Values: []string{"tokyo", "osaka"}, IsEnforced: true},
+			{Name: "message"},
+		},
+	}
+
+	problems := jobDef.validate(optionArgs{
+		{name: "env", value: "prod"},
+		{name: "evn", value: "prod"},
+		{name: "message", value: "a"},
+		{name: "message", value: "b"},
+		{name: "region", value: "nagoya"},
+	})
+
+	expectProblems := []string{
+		"unknown option '-evn'",
+		"duplicate option '-message'",
+		"option '-region' does not allow 'nagoya' (allowed: tokyo, osaka)",
+		"missing required option '-branch'",
+	}
+	if !reflect.DeepEqual(problems, expectProblems) {
+		t.Errorf("problems not match. got:%v, expect:%v", problems, expectProblems)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	opt := JobOption{Name: "region", Values: []string{"tokyo", "osaka"}}
+	if !opt.Allows("nagoya") {
+		t.Error("option not enforced should allow any value")
+	}
+
+	opt.IsEnforced = true
+	if !opt.Allows("osaka") {
+		t.Error("option should allow 'osaka'")
+	}
+	if opt.Allows("nagoya") {
+		t.Error("option should not allow 'nagoya'")
+	}
+
+	opt.IsMultiValued = true
+	if !opt.Allows("tokyo,osaka") {
+		t.Error("option should allow 'tokyo,osaka'")
+	}
+	if opt.Allows("tokyo,nagoya") {
+		t.Error("option should not allow 'tokyo,nagoya'")
+	}
+}
+
+func TestMissingOptions(t *testing.T) {
+	jobDef := JobDef{
+		Name: "deploy",
+		Opts: []JobOption{
+			{Name: "env", IsRequired: true},
+			{Name: "branch", IsRequired: true},
+			{Name: "region", IsRequired: true, Value: "tokyo"},
+			{Name: "message"},
+		},
+	}
+
+	missing := jobDef.MissingOptions([]string{"-env", "prod"})
+	if len(missing) != 1 || missing[0].Name != "branch" {
+		t.Errorf("missing options not match. got:%v, expect:%s", missing, "[branch]")
+	}
+}
+
+func TestJobOptionYAML(t *testing.T) {
+	testDef := `- name: deploy
+  description: deploy
+  options:
+  - name: region
+    description: target region
+    required: true
+    enforced: true
+    multivalued: true
+    delimiter: ','
+    value: tokyo
+    values:
+    - tokyo
+    - osaka
+  - name: password
+    secure: true
+    valueExposed: true
+`
+
+	var jdl JobDefList
+	if err := yaml.Unmarshal([]byte(testDef), &jdl); err != nil {
+		t.Fatal(err)
+	}
+
+	expectOpt := JobOption{
+		Name:          "region",
+		IsRequired:    true,
+		Desc:          "target region",
+		Value:         "tokyo",
+		Values:        []string{"tokyo", "osaka"},
+		IsEnforced:    true,
+		IsMultiValued: true,
+		Delimiter:     ",",
+	}
+	if len(jdl) != 1 || len(jdl[0].Opts) != 2 || !reflect.DeepEqual(jdl[0].Opts[0], expectOpt) {
+		t.Errorf("job option not match. got:%v, expect:%v", jdl, expectOpt)
+	}
+
+	if opt := jdl[0].Opts[1]; !opt.IsSecure || !opt.IsExposed || !opt.Secret() {
+		t.Errorf("job option should be secure and exposed. got:%v", opt)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	jobDef := JobDef{
+		Name: "deploy",
+		Opts: []JobOption{
+			{Name: "user"},
+			{Name: "password", IsSecure: true},
+			{Name: "token", IsSecure: true, IsExposed: true},
+		},
+	}
+
+	redactedArgs := jobDef.Redact([]string{"-user", "admin", "-password", "p@ss", "word", "-token", "abc", "--detach"})
+
+	expectArgs := []string{"-user", "admin", "-password", "****", "-token", "****", "--detach"}
+	if !reflect.DeepEqual(redactedArgs, expectArgs) {
+		t.Errorf("redacted args not match. got:%v, expect:%v", redactedArgs, expectArgs)
+	}
+
+	redactedArgs = jobDef.Redact([]string{"deploy", "-password", "-Xyz123", "-token", "--abc", "--detach"})
+
+	expectArgs = []string{"deploy", "-password", "****", "-token", "****", "--detach"}
+	if !reflect.DeepEqual(redactedArgs, expectArgs) {
+		t.Errorf("redacted args not match. got:%v, expect:%v", redactedArgs, expectArgs)
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	jobDef := JobDef{
+		Name: "deploy",
+		Opts: []JobOption{
+			{Name: "env"},
+			{Name: "branch"},
+			{Name: "regions", IsMultiValued: true, Delimiter: " "},
+			{Name: "count"},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "rundeck-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("RUNDECK_CLI_TEST_BRANCH", "release")
+	defer os.Unsetenv("RUNDECK_CLI_TEST_BRANCH")
+
+	yamlFile := filepath.Join(dir, "params.yaml")
+	ioutil.WriteFile(yamlFile, []byte(`env: staging
+branch: ${RUNDECK_CLI_TEST_BRANCH}
+regions:
+  - tokyo
+  - osaka
+count: 3
+`), 0600)
+
+	jsonFile := filepath.Join(dir, "params.json")
+	ioutil.WriteFile(jsonFile, []byte(`{
+	"env": "staging",
+	"count": 3,
+	"branch": "${RUNDECK_CLI_TEST_BRANCH}"
+}`), 0600)
+
+	t.Run("yaml", func(t *testing.T) {
+		oas, problems, err := jobDef.resolveOptions([]string{"-env", "prod"}, map[string]string{"options-file": yamlFile})
+		if err != nil || len(problems) > 0 {
+			t.Errorf("should not fail. err:%v, problems:%v", err, problems)
+		}
+
+		expectArgs := optionArgs{
+			{name: "branch", value: "release"},
+			{name: "regions", value: "tokyo osaka"},
+			{name: "count", value: "3"},
+			{name: "env", value: "prod"},
+		}
+		if !reflect.DeepEqual(oas, expectArgs) {
+			t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		oas, _, err := jobDef.resolveOptions([]string{}, map[string]string{"options-file": jsonFile})
+		if err != nil {
+			t.Error(err)
+		}
+
+		expectArgs := optionArgs{
+			{name: "branch", value: "release"},
+			{name: "count", value: "3"},
+			{name: "env", value: "staging"},
+		}
+		if !reflect.DeepEqual(oas, expectArgs) {
+			t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+		}
+	})
+
+	t.Run("values as written", func(t *testing.T) {
+		literalFile := filepath.Join(dir, "literal.yaml")
+		ioutil.WriteFile(literalFile, []byte(`country: NO
+version: 1.10
+confirm: yes
+zip: 0123
+password: pa$$word
+template: $${RUNDECK_CLI_TEST_BRANCH}
+empty:
+list: [NO, 1.10]
+`), 0600)
+
+		oas, err := jobDef.loadOptionsFile(literalFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectArgs := optionArgs{
+			{name: "country", value: "NO"},
+			{name: "version", value: "1.10"},
+			{name: "confirm", value: "yes"},
+			{name: "zip", value: "0123"},
+			{name: "password", value: "pa$$word"},
+			{name: "template", value: "${RUNDECK_CLI_TEST_BRANCH}"},
+			{name: "empty", value: ""},
+			{name: "list", value: "NO,1.10"},
+		}
+		if !reflect.DeepEqual(oas, expectArgs) {
+			t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+		}
+
+		literalJSON := filepath.Join(dir, "literal.json")
+		ioutil.WriteFile(literalJSON, []byte(`{"version": 1.10, "password": "pa$$word", "list": [1.10, "NO", true]}`), 0600)
+
+		oas, err = jobDef.loadOptionsFile(literalJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectArgs = optionArgs{
+			{name: "list", value: "1.10,NO,true"},
+			{name: "password", value: "pa$$word"},
+			{name: "version", value: "1.10"},
+		}
+		if !reflect.DeepEqual(oas, expectArgs) {
+			t.Errorf("option args not match. got:%v, expect:%v", oas, expectArgs)
+		}
+	})
+
+	t.Run("nested values", func(t *testing.T) {
+		nestedFile := filepath.Join(dir, "nested.yaml")
+		ioutil.WriteFile(nestedFile, []byte("env:\n  name: prod\n"), 0600)
+
+		expectErr := fmt.Sprintf("option 'env' in options file(%s) must be a value or a list", nestedFile)
+		if _, err := jobDef.loadOptionsFile(nestedFile); err == nil || err.Error() != expectErr {
+			t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+		}
+	})
+
+	t.Run("missing options", func(t *testing.T) {
+		def := JobDef{Opts: []JobOption{{Name: "env", IsRequired: true}, {Name: "token", IsRequired: true}}}
+
+		missing := def.MissingOptions([]string{"--options-file", yamlFile})
+		if len(missing) != 1 || missing[0].Name != "token" {
+			t.Errorf("missing options not match. got:%v, expect:%s", missing, "[token]")
+		}
+	})
+}
+
+func TestOptionValues(t *testing.T) {
+	hits := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+
+		switch r.URL.Path {
+		case "/strings":
+			w.Write([]byte(`["tokyo", "osaka"]`))
+		case "/objects":
+			w.Write([]byte(`[{"name": "Tokyo", "value": "tokyo"}, {"name": "Osaka", "value": "osaka"}]`))
+		case "/map":
+			w.Write([]byte(`{"Tokyo": "tokyo", "Osaka": "osaka"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	rd, err := AuthWithToken("token", "http", "localhost", "test-rundeck", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	opt := JobOption{Name: "region", Values: []string{"nagoya"}}
+	if values, err := rd.OptionValues(opt); err != nil || !reflect.DeepEqual(values, opt.Values) {
+		t.Errorf("values not match. got:%v, expect:%v", values, opt.Values)
+	}
+
+	for _, p := range []string{"/strings", "/objects", "/map"} {
+		opt.ValuesURL = ts.URL + p
+
+		values, err := rd.OptionValues(opt)
+		if err != nil {
+			t.Error(err)
+		}
+
+		expectValues := []string{"tokyo", "osaka"}
+		if p == "/map" {
+			expectValues = []string{"osaka", "tokyo"}
+		}
+		if !reflect.DeepEqual(values, expectValues) {
+			t.Errorf("values not match. path:%s, got:%v, expect:%v", p, values, expectValues)
+		}
+	}
+
+	opt.ValuesURL = ts.URL + "/strings"
+	if _, err := rd.OptionValues(opt); err != nil {
+		t.Error(err)
+	}
+	if hits != 3 {
+		t.Errorf("values should be cached. hits:%d, expect:%d", hits, 3)
+	}
+
+	opt.ValuesURL = ts.URL + "/none"
+	for i := 0; i < 2; i++ {
+		if _, err := rd.OptionValues(opt); err == nil {
+			t.Error("should return error message")
+		}
+	}
+	if hits != 4 {
+		t.Errorf("failures should be cached. hits:%d, expect:%d", hits, 4)
+	}
+
+	jobDef := JobDef{Opts: []JobOption{
+		{Name: "region", ValuesURL: ts.URL + "/strings"},
+		{Name: "zone", ValuesURL: ts.URL + "/none"},
+		{Name: "env", Values: []string{"prod"}},
+	}}
+	jobDef.fetchValues(rd)
+
+	if expect := []string{"tokyo", "osaka"}; !reflect.DeepEqual(jobDef.Opts[0].Values, expect) {
+		t.Errorf("values not match. got:%v, expect:%v", jobDef.Opts[0].Values, expect)
+	}
+
+	expectErr := fmt.Sprintf("failed to fetch values of option '-zone': %s", "404 Not Found")
+	if err := jobDef.Opts[1].ValuesErr; err == nil || err.Error() != expectErr {
+		t.Errorf("error message not match. got:%v, expect:%s", err, expectErr)
+	}
+}
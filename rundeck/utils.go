@@ -1,14 +1,21 @@
 package rundeck
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	re1 = regexp.MustCompile(`(?i)[^-_a-z0-9 ]`)
+	re1 = regexp.MustCompile(`[^-_\p{L}\p{M}\p{N} ]`)
 	re2 = regexp.MustCompile(`[ _-]+`)
 	re3 = regexp.MustCompile(`^-`)
+
+	reJobID     = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	rePermalink = regexp.MustCompile(`^https?://.*/job/show/([^/?#]+)`)
 )
 
 func normalize(s string) string {
@@ -18,3 +25,222 @@ func normalize(s string) string {
 
 	return strings.ToLower(s)
 }
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// label builds the label of a job from its group path and name, such as "web/deploy".
+// A name with nothing left to label falls back to its ID, such as "job-1a2b3c4d".
+func label(group, name, id string) string {
+	segs := make([]string, 0, 4)
+	for _, g := range strings.Split(group, "/") {
+		if g = normalize(g); g != "" {
+			segs = append(segs, g)
+		}
+	}
+
+	n := normalize(name)
+	if n == "" {
+		n = "job-" + shortID(id)
+	}
+
+	return strings.Join(append(segs, n), "/")
+}
+
+// shortID returns the first 8 characters of a job ID.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+const maxSuggestions = 3
+
+// isSubsequence reports whether the runes of s appear in t in order, such as "dply" in "deploy".
+func isSubsequence(s, t string) bool {
+	rs := []rune(s)
+	for _, c := range t {
+		if len(rs) == 0 {
+			break
+		}
+		if c == rs[0] {
+			rs = rs[1:]
+		}
+	}
+
+	return len(rs) == 0
+}
+
+// editDistance returns the Levenshtein distance between s and t.
+func editDistance(s, t string) int {
+	rs, rt := []rune(s), []rune(t)
+
+	prev := make([]int, len(rt)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(rs); i++ {
+		cur := make([]int, len(rt)+1)
+		cur[0] = i
+		for j := 1; j <= len(rt); j++ {
+			cost := 1
+			if rs[i-1] == rt[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev = cur
+	}
+
+	return prev[len(rt)]
+}
+
+// suggest returns the labels closest to s, subsequence matches first and then by edit distance.
+// The last segment of a grouped label is also compared, so "deploy" finds "web/deploy".
+func suggest(s string, labels []string) []string {
+	type candidate struct {
+		label  string
+		subseq bool
+		dist   int
+	}
+
+	limit := len([]rune(s)) / 3
+	if limit < 2 {
+		limit = 2
+	}
+
+	var cands []candidate
+	for _, l := range labels {
+		d := editDistance(s, l)
+		if i := strings.LastIndex(l, "/"); i >= 0 {
+			if dd := editDistance(s, l[i+1:]); dd < d {
+				d = dd
+			}
+		}
+
+		sub := isSubsequence(s, l)
+		if !sub && d > limit {
+			continue
+		}
+
+		cands = append(cands, candidate{label: l, subseq: sub, dist: d})
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].subseq != cands[j].subseq {
+			return cands[i].subseq
+		}
+		return cands[i].dist < cands[j].dist
+	})
+
+	list := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(cands) && i < maxSuggestions; i++ {
+		list = append(list, cands[i].label)
+	}
+
+	return list
+}
+
+// parseJobID returns the job ID that s refers to when s is a job UUID or permalink.
+func parseJobID(s string) (string, bool) {
+	if reJobID.MatchString(s) {
+		return s, true
+	}
+
+	if m := rePermalink.FindStringSubmatch(s); m != nil {
+		return m[1], true
+	}
+
+	return "", false
+}
+
+// parseFlags separates "--name" and "--name=value" flags from the other args.
+// spec maps each known flag name to whether it takes a value.
+// The arg after a "-name" job option is its value and is never taken as a flag.
+func parseFlags(args []string, spec map[string]bool) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	rest := make([]string, 0, len(args))
+	afterOpt := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if afterOpt || !strings.HasPrefix(arg, "--") || arg == "--" {
+			afterOpt = !afterOpt && reOptName.MatchString(arg)
+			rest = append(rest, arg)
+			continue
+		}
+
+		name, value := strings.TrimPrefix(arg, "--"), ""
+		hasValue := false
+		if n := strings.Index(name, "="); n >= 0 {
+			name, value, hasValue = name[:n], name[n+1:], true
+		}
+
+		valued, ok := spec[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown flag '--%s'", name)
+		}
+
+		switch {
+		case !valued:
+			value = "true"
+		case !hasValue:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag '--%s' requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		flags[name] = value
+	}
+
+	return flags, rest, nil
+}
+
+func parseExecutionID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid execution id '%s'", s)
+	}
+
+	return id, nil
+}
+
+// parseRunAt parses the time to run a job at, either an RFC3339 time
+// or a duration relative to now such as "+2h" or "+1h30m".
+func parseRunAt(s string, now time.Time) (time.Time, error) {
+	var t time.Time
+	if strings.HasPrefix(s, "+") {
+		d, err := time.ParseDuration(s[1:])
+		if err != nil {
+			return t, fmt.Errorf("invalid time '%s'", s)
+		}
+		t = now.Add(d)
+	} else {
+		var err error
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return t, fmt.Errorf("invalid time '%s'", s)
+		}
+	}
+
+	if !t.After(now) {
+		return t, fmt.Errorf("time '%s' is not in the future", s)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,136 @@
+package rundeck
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	spec := map[string]bool{"detach": false, "filter": true, "at": true}
+
+	flags, rest, err := parseFlags([]string{"deploy", "--detach", "-env", "prod", "--filter", "tags: web", "--at=+2h"}, spec)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectFlags := map[string]string{"detach": "true", "filter": "tags: web", "at": "+2h"}
+	if !reflect.DeepEqual(flags, expectFlags) {
+		t.Errorf("flags not match. got:%v, expect:%v", flags, expectFlags)
+	}
+
+	expectRest := []string{"deploy", "-env", "prod"}
+	if !reflect.DeepEqual(rest, expectRest) {
+		t.Errorf("rest not match. got:%v, expect:%v", rest, expectRest)
+	}
+
+	if _, _, err := parseFlags([]string{"--pppp"}, spec); err == nil || err.Error() != "unknown flag '--pppp'" {
+		t.Errorf("error message not match. got:%v, expect:%s", err, "unknown flag '--pppp'")
+	}
+
+	_, rest, err = parseFlags([]string{"deploy", "-password", "--pppp", "--detach"}, spec)
+	if err != nil {
+		t.Error(err)
+	}
+	if expect := []string{"deploy", "-password", "--pppp"}; !reflect.DeepEqual(rest, expect) {
+		t.Errorf("rest not match. got:%v, expect:%v", rest, expect)
+	}
+
+	if _, _, err := parseFlags([]string{"--filter"}, spec); err == nil || err.Error() != "flag '--filter' requires a value" {
+		t.Errorf("error message not match. got:%v, expect:%s", err, "flag '--filter' requires a value")
+	}
+}
+
+func TestParseRunAt(t *testing.T) {
+	now := time.Date(2016, 11, 1, 15, 0, 0, 0, time.UTC)
+
+	at, err := parseRunAt("+1h30m", now)
+	if err != nil {
+		t.Error(err)
+	}
+	if expect := now.Add(90 * time.Minute); !at.Equal(expect) {
+		t.Errorf("time not match. got:%s, expect:%s", at, expect)
+	}
+
+	at, err = parseRunAt("2016-11-02T09:00:00+09:00", now)
+	if err != nil {
+		t.Error(err)
+	}
+	if s := at.Format(runAtTimeFmt); s != "2016-11-02T09:00:00+0900" {
+		t.Errorf("time not match. got:%s, expect:%s", s, "2016-11-02T09:00:00+0900")
+	}
+
+	if _, err := parseRunAt("tomorrow", now); err == nil || err.Error() != "invalid time 'tomorrow'" {
+		t.Errorf("error message not match. got:%v, expect:%s", err, "invalid time 'tomorrow'")
+	}
+
+	if _, err := parseRunAt("2016-11-01T00:00:00Z", now); err == nil || err.Error() != "time '2016-11-01T00:00:00Z' is not in the future" {
+		t.Errorf("error message not match. got:%v, expect:%s", err, "time '2016-11-01T00:00:00Z' is not in the future")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		group, name, id string
+		expect          string
+	}{
+		{"", "Deploy App", "1a2b3c4d-0000", "deploy-app"},
+		{"web/App Servers", "restart", "1a2b3c4d-0000", "web/app-servers/restart"},
+		{"", "デプロイ 本番", "1a2b3c4d-0000", "デプロイ-本番"},
+		{"運用", "Über Job!", "1a2b3c4d-0000", "運用/über-job"},
+		{"", "ติดตั้ง", "1a2b3c4d-0000", "ติดตั้ง"},
+		{"", "डिप्लॉय ऐप", "1a2b3c4d-0000", "डिप्लॉय-ऐप"},
+		{"", "🚀🚀", "1a2b3c4d-0000", "job-1a2b3c4d"},
+	}
+
+	for _, tt := range tests {
+		if l := label(tt.group, tt.name, tt.id); l != tt.expect {
+			t.Errorf("label not match. got:%s, expect:%s", l, tt.expect)
+		}
+	}
+}
+
+func TestParseJobID(t *testing.T) {
+	tests := []struct {
+		s      string
+		expect string
+		ok     bool
+	}{
+		{"3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d", "3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d", true},
+		{"http://rundeck.in/project/test/job/show/3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d", "3d2a5b8c-6f1e-4d7a-9b0c-1e2f3a4b5c6d", true},
+		{"https://rundeck.in/job/show/12?x=1", "12", true},
+		{"deploy", "", false},
+		{"web/deploy", "", false},
+	}
+
+	for _, tt := range tests {
+		id, ok := parseJobID(tt.s)
+		if id != tt.expect || ok != tt.ok {
+			t.Errorf("job id not match. got:%s,%v, expect:%s,%v", id, ok, tt.expect, tt.ok)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	labels := []string{"deploy", "web/deploy", "db/backup", "restart-app", "cleanup"}
+
+	tests := []struct {
+		s      string
+		expect []string
+	}{
+		{"deplyo", []string{"deploy", "web/deploy"}},
+		{"rstrt", []string{"restart-app"}},
+		{"backpu", []string{"db/backup"}},
+		{"zzzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		if list := suggest(tt.s, labels); !reflect.DeepEqual(list, tt.expect) {
+			t.Errorf("suggestions not match. s:%s, got:%v, expect:%v", tt.s, list, tt.expect)
+		}
+	}
+
+	if d := editDistance("kitten", "sitting"); d != 3 {
+		t.Errorf("edit distance not match. got:%d, expect:%d", d, 3)
+	}
+}